@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"encoding/json"
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"i9-pos/platform/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetExercises(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		exercises, err := database.AllExercises(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying exercises", err)
+			return
+		}
+
+		c.JSON(200, exercises)
+	}
+}
+
+func GetExercise(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		exer, err := database.ExerciseByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying exercise", err)
+			return
+		}
+
+		c.JSON(200, exer)
+	}
+}
+
+func PostExercise(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var exer datatypes.Exercise
+		if err := c.ShouldBindJSON(&exer); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !validate(c, catalog.ValidateExercise(exer)) {
+			return
+		}
+
+		created, err := database.InsertExercise(db, boltDB, tenant, exer)
+		if err != nil {
+			writeSampleError(c, "Issue with creating exercise", err)
+			return
+		}
+
+		c.JSON(201, created)
+	}
+}
+
+func PutExercise(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var exer datatypes.Exercise
+		if err := c.ShouldBindJSON(&exer); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &exer.BackendID) {
+			return
+		}
+
+		if !validate(c, catalog.ValidateExercise(exer)) {
+			return
+		}
+
+		updated, err := database.ReplaceExercise(db, boltDB, tenant, exer)
+		if err != nil {
+			writeSampleError(c, "Issue with updating exercise", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+// PatchExercise overlays the fields present in the body on the stored
+// exercise and validates the result as a whole.
+func PatchExercise(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		exer, err := database.ExerciseByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying exercise", err)
+			return
+		}
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&exer); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &exer.BackendID) {
+			return
+		}
+
+		if !validate(c, catalog.ValidateExercise(exer)) {
+			return
+		}
+
+		updated, err := database.ReplaceExercise(db, boltDB, tenant, exer)
+		if err != nil {
+			writeSampleError(c, "Issue with updating exercise", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+func DeleteExercise(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		if err := database.DeleteExercise(db, boltDB, tenant, c.Param("id")); err != nil {
+			writeSampleError(c, "Issue with deleting exercise", err)
+			return
+		}
+
+		c.Status(204)
+	}
+}
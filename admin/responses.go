@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"errors"
+	"i9-pos/database"
+	"i9-pos/platform/apierror"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// writeError answers 404 for an unknown document, 409 for a BackendID that's
+// taken and 503 for anything else, which is a Mongo or cache failure.
+func writeError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		apierror.NotFound(c, message)
+	case errors.Is(err, database.ErrDuplicateBackendID):
+		apierror.Conflict(c, message, err.Error())
+	default:
+		apierror.Unavailable(c, message, err)
+	}
+}
+
+func writeBindError(c *gin.Context, err error) {
+	apierror.BadRequest(c, "Issue with body binding", err.Error())
+}
+
+// validate answers 400 with the problems found, if any, and reports whether
+// the document is valid.
+func validate(c *gin.Context, problems []string) bool {
+	if len(problems) == 0 {
+		return true
+	}
+
+	apierror.Validation(c, problems)
+	return false
+}
+
+// matchBackendID fills in the BackendID from the URL, rejecting a body that
+// names a different one.
+func matchBackendID(c *gin.Context, backendID *string) bool {
+	id := c.Param("id")
+
+	if *backendID != "" && *backendID != id {
+		apierror.BadRequest(c, "Issue with param", "BackendID in body doesn't match the URL")
+		return false
+	}
+
+	*backendID = id
+	return true
+}
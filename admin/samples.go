@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"errors"
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"i9-pos/platform/apierror"
+	"i9-pos/platform/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func PostSample(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var sample datatypes.Sample
+		if err := c.ShouldBindJSON(&sample); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !validate(c, catalog.ValidateSample(sample)) {
+			return
+		}
+
+		created, err := database.InsertLinkedSample(db, boltDB, tenant, sample)
+		if err != nil {
+			writeSampleError(c, "Issue with creating sample", err)
+			return
+		}
+
+		c.JSON(201, created)
+	}
+}
+
+func PutSample(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var sample datatypes.Sample
+		if err := c.ShouldBindJSON(&sample); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		id, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying sample", mongo.ErrNoDocuments)
+			return
+		}
+
+		if !sample.ID.IsZero() && sample.ID != id {
+			apierror.BadRequest(c, "Issue with param", "ID in body doesn't match the URL")
+			return
+		}
+		sample.ID = id
+
+		if !validate(c, catalog.ValidateSample(sample)) {
+			return
+		}
+
+		updated, err := database.ReplaceLinkedSample(db, boltDB, tenant, sample)
+		if err != nil {
+			writeSampleError(c, "Issue with updating sample", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+func DeleteSample(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		if err := database.DeleteLinkedSample(db, boltDB, tenant, c.Param("id")); err != nil {
+			writeSampleError(c, "Issue with deleting sample", err)
+			return
+		}
+
+		c.Status(204)
+	}
+}
+
+// GetSampleReport lists samples whose exercise or stretch is missing or
+// links elsewhere, and exercises and stretches whose SampleID points nowhere.
+func GetSampleReport(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		samples, err := database.AllSamples(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying samples", err)
+			return
+		}
+
+		exercises, err := database.AllExercises(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying exercises", err)
+			return
+		}
+
+		dynamics, err := database.AllDynamics(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying dynamic stretches", err)
+			return
+		}
+
+		statics, err := database.AllStatics(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying static stretches", err)
+			return
+		}
+
+		c.JSON(200, catalog.LinkReport(samples, exercises, dynamics, statics))
+	}
+}
+
+// writeSampleError answers 422 when a sample and its exercise or stretch
+// would point at something the catalog doesn't hold, and 409 when the link is
+// taken or still in use.
+func writeSampleError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, database.ErrUnknownSampleTarget), errors.Is(err, database.ErrSampleIDMismatch):
+		apierror.Unresolvable(c, message, err.Error())
+	case errors.Is(err, database.ErrSampleTargetLinked), errors.Is(err, database.ErrSampleStillLinked):
+		apierror.Conflict(c, message, err.Error())
+	default:
+		writeError(c, message, err)
+	}
+}
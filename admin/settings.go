@@ -0,0 +1,37 @@
+package admin
+
+import (
+	"i9-pos/database"
+	"i9-pos/platform/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type DefaultThemeRequest struct {
+	DefaultTheme string
+}
+
+// PutDefaultTheme sets the theme the tenant's workouts use when they don't
+// ask for one. An empty DefaultTheme goes back to the settings positions.
+func PutDefaultTheme(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var req DefaultThemeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		settings, err := database.SetDefaultTheme(db, boltDB, tenant, req.DefaultTheme)
+		if err != nil {
+			writeThemeError(c, "Issue with setting default theme", err)
+			return
+		}
+
+		c.JSON(200, settings)
+	}
+}
@@ -0,0 +1,281 @@
+package admin
+
+import (
+	"encoding/json"
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"i9-pos/platform/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetDynamics(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		dynamics, err := database.AllDynamics(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying dynamic stretches", err)
+			return
+		}
+
+		c.JSON(200, dynamics)
+	}
+}
+
+func GetDynamic(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		dynamic, err := database.DynamicByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying dynamic stretch", err)
+			return
+		}
+
+		c.JSON(200, dynamic)
+	}
+}
+
+func PostDynamic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var dynamic datatypes.DynamicStr
+		if err := c.ShouldBindJSON(&dynamic); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !validate(c, catalog.ValidateDynamic(dynamic)) {
+			return
+		}
+
+		created, err := database.InsertDynamic(db, boltDB, tenant, dynamic)
+		if err != nil {
+			writeSampleError(c, "Issue with creating dynamic stretch", err)
+			return
+		}
+
+		c.JSON(201, created)
+	}
+}
+
+func PutDynamic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var dynamic datatypes.DynamicStr
+		if err := c.ShouldBindJSON(&dynamic); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &dynamic.BackendID) {
+			return
+		}
+
+		if !validate(c, catalog.ValidateDynamic(dynamic)) {
+			return
+		}
+
+		updated, err := database.ReplaceDynamic(db, boltDB, tenant, dynamic)
+		if err != nil {
+			writeSampleError(c, "Issue with updating dynamic stretch", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+// PatchDynamic overlays the fields present in the body on the stored
+// dynamic stretch and validates the result as a whole.
+func PatchDynamic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		dynamic, err := database.DynamicByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying dynamic stretch", err)
+			return
+		}
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&dynamic); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &dynamic.BackendID) {
+			return
+		}
+
+		if !validate(c, catalog.ValidateDynamic(dynamic)) {
+			return
+		}
+
+		updated, err := database.ReplaceDynamic(db, boltDB, tenant, dynamic)
+		if err != nil {
+			writeSampleError(c, "Issue with updating dynamic stretch", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+func DeleteDynamic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		if err := database.DeleteDynamic(db, boltDB, tenant, c.Param("id")); err != nil {
+			writeSampleError(c, "Issue with deleting dynamic stretch", err)
+			return
+		}
+
+		c.Status(204)
+	}
+}
+
+func GetStatics(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		statics, err := database.AllStatics(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying static stretches", err)
+			return
+		}
+
+		c.JSON(200, statics)
+	}
+}
+
+func GetStatic(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		static, err := database.StaticByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying static stretch", err)
+			return
+		}
+
+		c.JSON(200, static)
+	}
+}
+
+func PostStatic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var static datatypes.StaticStr
+		if err := c.ShouldBindJSON(&static); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !validate(c, catalog.ValidateStatic(static)) {
+			return
+		}
+
+		created, err := database.InsertStatic(db, boltDB, tenant, static)
+		if err != nil {
+			writeSampleError(c, "Issue with creating static stretch", err)
+			return
+		}
+
+		c.JSON(201, created)
+	}
+}
+
+func PutStatic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var static datatypes.StaticStr
+		if err := c.ShouldBindJSON(&static); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &static.BackendID) {
+			return
+		}
+
+		if !validate(c, catalog.ValidateStatic(static)) {
+			return
+		}
+
+		updated, err := database.ReplaceStatic(db, boltDB, tenant, static)
+		if err != nil {
+			writeSampleError(c, "Issue with updating static stretch", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+// PatchStatic overlays the fields present in the body on the stored
+// static stretch and validates the result as a whole.
+func PatchStatic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		static, err := database.StaticByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying static stretch", err)
+			return
+		}
+
+		if err := json.NewDecoder(c.Request.Body).Decode(&static); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &static.BackendID) {
+			return
+		}
+
+		if !validate(c, catalog.ValidateStatic(static)) {
+			return
+		}
+
+		updated, err := database.ReplaceStatic(db, boltDB, tenant, static)
+		if err != nil {
+			writeSampleError(c, "Issue with updating static stretch", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+func DeleteStatic(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		if err := database.DeleteStatic(db, boltDB, tenant, c.Param("id")); err != nil {
+			writeSampleError(c, "Issue with deleting static stretch", err)
+			return
+		}
+
+		c.Status(204)
+	}
+}
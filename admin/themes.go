@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"errors"
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"i9-pos/platform/apierror"
+	"i9-pos/platform/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetThemes(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		themes, err := database.AllThemes(db, tenant)
+		if err != nil {
+			writeError(c, "Issue with querying themes", err)
+			return
+		}
+
+		c.JSON(200, themes)
+	}
+}
+
+func GetTheme(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		theme, err := database.ThemeByBackendID(db, tenant, c.Param("id"))
+		if err != nil {
+			writeError(c, "Issue with querying theme", err)
+			return
+		}
+
+		c.JSON(200, theme)
+	}
+}
+
+func PostTheme(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var theme datatypes.Theme
+		if err := c.ShouldBindJSON(&theme); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !validateTheme(c, db, boltDB, tenant, theme) {
+			return
+		}
+
+		created, err := database.InsertTheme(db, boltDB, tenant, theme)
+		if err != nil {
+			writeError(c, "Issue with creating theme", err)
+			return
+		}
+
+		c.JSON(201, created)
+	}
+}
+
+func PutTheme(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		var theme datatypes.Theme
+		if err := c.ShouldBindJSON(&theme); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !matchBackendID(c, &theme.BackendID) {
+			return
+		}
+
+		if !validateTheme(c, db, boltDB, tenant, theme) {
+			return
+		}
+
+		updated, err := database.ReplaceTheme(db, boltDB, tenant, theme)
+		if err != nil {
+			writeError(c, "Issue with updating theme", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+func DeleteTheme(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		if err := database.DeleteTheme(db, boltDB, tenant, c.Param("id")); err != nil {
+			writeThemeError(c, "Issue with deleting theme", err)
+			return
+		}
+
+		c.Status(204)
+	}
+}
+
+// validateTheme checks the theme's positions against the image sets the
+// tenant's catalog shows, plus those ThemeImageSets allows.
+func validateTheme(c *gin.Context, db *mongo.Database, boltDB *bbolt.DB, tenant string, theme datatypes.Theme) bool {
+	cat, err := database.LoadCatalog(db, tenant)
+	if err != nil {
+		writeError(c, "Issue with querying catalog", err)
+		return false
+	}
+
+	settings, err := database.GetTenantSettings(db, boltDB, tenant)
+	if err != nil {
+		writeError(c, "Issue with querying tenant settings", err)
+		return false
+	}
+
+	known := catalog.KnownImageSets(cat, database.ThemeImageSets(settings))
+
+	return validate(c, catalog.ValidateTheme(theme, known))
+}
+
+// writeThemeError answers 422 for a theme the catalog doesn't hold and 409
+// for deleting the tenant's default theme.
+func writeThemeError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, database.ErrUnknownTheme):
+		apierror.Unresolvable(c, message, err.Error())
+	case errors.Is(err, database.ErrDefaultTheme):
+		apierror.Conflict(c, message, err.Error())
+	default:
+		writeError(c, message, err)
+	}
+}
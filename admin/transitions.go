@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"i9-pos/platform/apierror"
+	"i9-pos/platform/middleware"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func GetTransition(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		from, to, ok := transitionParams(c)
+		if !ok {
+			return
+		}
+
+		rep, err := database.TransitionCell(db, tenant, c.Param("speed"), from, to)
+		if err != nil {
+			writeError(c, "Issue with querying transition", err)
+			return
+		}
+
+		c.JSON(200, rep)
+	}
+}
+
+// PutTransition replaces one cell of the transition matrix and records who
+// changed it.
+func PutTransition(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		if _, _, ok := transitionParams(c); !ok {
+			return
+		}
+
+		var rep datatypes.TransitionRep
+		if err := c.ShouldBindJSON(&rep); err != nil {
+			writeBindError(c, err)
+			return
+		}
+
+		if !validate(c, catalog.ValidateTransitionRep(rep)) {
+			return
+		}
+
+		principal, _ := middleware.GetPrincipal(c)
+
+		updated, err := database.UpdateTransitionCell(db, boltDB, tenant, c.Param("speed"), c.Param("from"), c.Param("to"), rep, principal.Provider+":"+principal.UID)
+		if err != nil {
+			writeError(c, "Issue with updating transition", err)
+			return
+		}
+
+		c.JSON(200, updated)
+	}
+}
+
+func GetTransitionHistory(db *mongo.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+		if err != nil || limit <= 0 {
+			apierror.BadRequest(c, "Issue with query", "limit must be a positive number")
+			return
+		}
+
+		history, err := database.TransitionHistory(db, tenant, limit)
+		if err != nil {
+			writeError(c, "Issue with querying transition history", err)
+			return
+		}
+
+		c.JSON(200, history)
+	}
+}
+
+func transitionParams(c *gin.Context) (int, int, bool) {
+	speed := c.Param("speed")
+	if speed != "fast" && speed != "regular" && speed != "slow" {
+		apierror.BadRequest(c, "Issue with param", database.ErrUnknownSpeed.Error())
+		return 0, 0, false
+	}
+
+	from, fromOK := datatypes.ParentMatIndex[c.Param("from")]
+	to, toOK := datatypes.ParentMatIndex[c.Param("to")]
+	if !fromOK || !toOK {
+		apierror.BadRequest(c, "Issue with param", "from and to must be known parent families")
+		return 0, 0, false
+	}
+
+	return from, to, true
+}
@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"i9-pos/datatypes"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Bump when the archive layout changes so old tools refuse new archives
+const ArchiveVersion = 2
+
+// Collections in an archive, in the order they are written and imported
+var ArchiveCollections = []string{"exercise", "dynamicstretch", "staticstretch", "sample", "transition", "theme"}
+
+// Archive version each collection first appeared in, when later than 1
+var collectionSince = map[string]int{"theme": 2}
+
+// WriteArchive zips a manifest.json and one <collection>.json per collection.
+// Documents are canonical extended JSON, one per line, so BSON types survive
+// and archives diff cleanly.
+func WriteArchive(w io.Writer, archive datatypes.CatalogArchive) error {
+	zipWriter := zip.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(archive.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeZipFile(zipWriter, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, collection := range ArchiveCollections {
+		var buf bytes.Buffer
+		buf.WriteString("[\n")
+
+		for i, doc := range archive.Collections[collection] {
+			extJSON, err := bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				return fmt.Errorf("%s[%d]: %w", collection, i, err)
+			}
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+			buf.Write(extJSON)
+		}
+
+		buf.WriteString("\n]\n")
+
+		if err := writeZipFile(zipWriter, collection+".json", buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+func writeZipFile(zipWriter *zip.Writer, name string, data []byte) error {
+	file, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	return err
+}
+
+// ReadArchive opens an archive written by WriteArchive, refusing versions
+// this build doesn't understand. Collections added after the archive's
+// version read as empty.
+func ReadArchive(path string) (datatypes.CatalogArchive, error) {
+	archive := datatypes.CatalogArchive{Collections: map[string][]bson.D{}}
+
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return archive, err
+	}
+	defer zipReader.Close()
+
+	manifest, err := readZipFile(&zipReader.Reader, "manifest.json")
+	if err != nil {
+		return archive, err
+	}
+
+	if err := json.Unmarshal(manifest, &archive.Manifest); err != nil {
+		return archive, fmt.Errorf("manifest.json: %w", err)
+	}
+
+	if archive.Manifest.Version < 1 || archive.Manifest.Version > ArchiveVersion {
+		return archive, fmt.Errorf("archive version %d is not supported, expected at most %d", archive.Manifest.Version, ArchiveVersion)
+	}
+
+	for _, collection := range ArchiveCollections {
+		if collectionSince[collection] > archive.Manifest.Version {
+			archive.Collections[collection] = []bson.D{}
+			continue
+		}
+
+		data, err := readZipFile(&zipReader.Reader, collection+".json")
+		if err != nil {
+			return archive, err
+		}
+
+		var rawDocs []json.RawMessage
+		if err := json.Unmarshal(data, &rawDocs); err != nil {
+			return archive, fmt.Errorf("%s.json: %w", collection, err)
+		}
+
+		docs := []bson.D{}
+		for i, raw := range rawDocs {
+			var doc bson.D
+			if err := bson.UnmarshalExtJSON(raw, true, &doc); err != nil {
+				return archive, fmt.Errorf("%s[%d]: %w", collection, i, err)
+			}
+			docs = append(docs, doc)
+		}
+
+		archive.Collections[collection] = docs
+	}
+
+	return archive, nil
+}
+
+func readZipFile(zipReader *zip.Reader, name string) ([]byte, error) {
+	file, err := zipReader.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("archive has no %s: %w", name, err)
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// ArchiveCatalog decodes an archive's raw documents into the catalog types.
+func ArchiveCatalog(archive datatypes.CatalogArchive) (datatypes.Catalog, error) {
+	var catalog datatypes.Catalog
+	var err error
+
+	if catalog.Exercises, err = decodeDocs[datatypes.Exercise](archive, "exercise"); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Dynamics, err = decodeDocs[datatypes.DynamicStr](archive, "dynamicstretch"); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Statics, err = decodeDocs[datatypes.StaticStr](archive, "staticstretch"); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Samples, err = decodeDocs[datatypes.Sample](archive, "sample"); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Transitions, err = decodeDocs[datatypes.TransitionMatrix](archive, "transition"); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Themes, err = decodeDocs[datatypes.Theme](archive, "theme"); err != nil {
+		return catalog, err
+	}
+
+	return catalog, nil
+}
+
+func decodeDocs[T any](archive datatypes.CatalogArchive, collection string) ([]T, error) {
+	docs := []T{}
+
+	for i, raw := range archive.Collections[collection] {
+		data, err := bson.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", collection, i, err)
+		}
+
+		var doc T
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", collection, i, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
@@ -0,0 +1,154 @@
+package catalog
+
+import (
+	"fmt"
+	"i9-pos/datatypes"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+var sampleCollections = map[string]string{
+	"Exercise":        "exercise",
+	"Dynamic Stretch": "dynamicstretch",
+	"Static Stretch":  "staticstretch",
+}
+
+// Lint checks a whole catalog. Every document and transition matrix cell
+// goes through the same validation as an admin write, BackendIDs must be
+// unique per collection, and broken sample links are reported as warnings. themeImageSets are the
+// image sets themes may use beyond those the catalog shows.
+func Lint(catalog datatypes.Catalog, themeImageSets []string) []datatypes.LintProblem {
+	problems := []datatypes.LintProblem{}
+
+	add := func(severity, collection, backendID string, found []string) {
+		for _, problem := range found {
+			problems = append(problems, datatypes.LintProblem{
+				Severity:   severity,
+				Collection: collection,
+				BackendID:  backendID,
+				Problem:    problem,
+			})
+		}
+	}
+
+	exerciseIDs := []string{}
+	for _, exer := range catalog.Exercises {
+		add(SeverityError, "exercise", exer.BackendID, ValidateExercise(exer))
+		exerciseIDs = append(exerciseIDs, exer.BackendID)
+	}
+
+	dynamicIDs := []string{}
+	for _, dynamic := range catalog.Dynamics {
+		add(SeverityError, "dynamicstretch", dynamic.BackendID, ValidateDynamic(dynamic))
+		dynamicIDs = append(dynamicIDs, dynamic.BackendID)
+	}
+
+	staticIDs := []string{}
+	for _, static := range catalog.Statics {
+		add(SeverityError, "staticstretch", static.BackendID, ValidateStatic(static))
+		staticIDs = append(staticIDs, static.BackendID)
+	}
+
+	known := KnownImageSets(catalog, themeImageSets)
+	themeIDs := []string{}
+	for _, theme := range catalog.Themes {
+		add(SeverityError, "theme", theme.BackendID, ValidateTheme(theme, known))
+		themeIDs = append(themeIDs, theme.BackendID)
+	}
+
+	for _, id := range duplicates(exerciseIDs) {
+		add(SeverityError, "exercise", id, []string{"BackendID is used more than once"})
+	}
+	for _, id := range duplicates(dynamicIDs) {
+		add(SeverityError, "dynamicstretch", id, []string{"BackendID is used more than once"})
+	}
+	for _, id := range duplicates(staticIDs) {
+		add(SeverityError, "staticstretch", id, []string{"BackendID is used more than once"})
+	}
+	for _, id := range duplicates(themeIDs) {
+		add(SeverityError, "theme", id, []string{"BackendID is used more than once"})
+	}
+
+	for _, sample := range catalog.Samples {
+		add(SeverityError, "sample", sample.ID.Hex(), ValidateSample(sample))
+	}
+
+	if len(catalog.Transitions) > 1 {
+		add(SeverityError, "transition", "", []string{fmt.Sprintf("%d transition matrices, workouts only use the first", len(catalog.Transitions))})
+	}
+	for _, matrix := range catalog.Transitions {
+		for _, cell := range transitionCells(matrix) {
+			add(SeverityError, "transition", cell.id, ValidateTransitionRep(cell.rep))
+		}
+	}
+
+	report := LinkReport(catalog.Samples, catalog.Exercises, catalog.Dynamics, catalog.Statics)
+	for _, orphan := range report.OrphanedSamples {
+		add(SeverityWarning, "sample", orphan.SampleID, []string{orphan.Problem})
+	}
+	for _, broken := range report.BrokenSampleIDs {
+		add(SeverityWarning, sampleCollections[broken.Type], broken.ExOrStID, []string{"SampleID " + broken.SampleID + " matches no sample"})
+	}
+
+	return problems
+}
+
+// HasErrors reports whether any problem is an error rather than a warning.
+func HasErrors(problems []datatypes.LintProblem) bool {
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicates lists each non-empty id that appears more than once, in the
+// order of its second appearance.
+func duplicates(ids []string) []string {
+	seen := map[string]int{}
+	dupes := []string{}
+	for _, id := range ids {
+		seen[id]++
+		if id != "" && seen[id] == 2 {
+			dupes = append(dupes, id)
+		}
+	}
+	return dupes
+}
+
+type transitionCell struct {
+	id  string
+	rep datatypes.TransitionRep
+}
+
+// transitionCells lists every cell of a matrix, named speed/from/to like the
+// admin transition routes.
+func transitionCells(matrix datatypes.TransitionMatrix) []transitionCell {
+	parents := make([]string, len(datatypes.ParentMatIndex))
+	for parent, index := range datatypes.ParentMatIndex {
+		parents[index] = parent
+	}
+
+	grids := []struct {
+		speed string
+		grid  *[11][11]datatypes.TransitionRep
+	}{
+		{"fast", &matrix.FastMatrix},
+		{"regular", &matrix.RegularMatrix},
+		{"slow", &matrix.SlowMatrix},
+	}
+
+	cells := []transitionCell{}
+	for _, g := range grids {
+		for from, row := range g.grid {
+			for to, rep := range row {
+				cells = append(cells, transitionCell{id: g.speed + "/" + parents[from] + "/" + parents[to], rep: rep})
+			}
+		}
+	}
+	return cells
+}
@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"i9-pos/datatypes"
+	"sort"
+)
+
+var SampleTypes = []string{"Exercise", "Dynamic Stretch", "Static Stretch"}
+
+// ValidateSample lists everything wrong with a sample's own fields. Whether
+// it links to a real exercise or stretch is checked against the database.
+func ValidateSample(sample datatypes.Sample) []string {
+	problems := []string{}
+
+	if sample.Name == "" {
+		problems = append(problems, "Name is required")
+	}
+
+	known := false
+	for _, sampleType := range SampleTypes {
+		if sample.Type == sampleType {
+			known = true
+		}
+	}
+	if !known {
+		problems = append(problems, "Type must be Exercise, Dynamic Stretch or Static Stretch")
+	}
+
+	if sample.ExOrStID == "" {
+		problems = append(problems, "ExOrStID is required")
+	}
+
+	if len(sample.Reps.Positions) != len(sample.Reps.Times) {
+		problems = append(problems, "Reps needs one time per position")
+	}
+
+	return problems
+}
+
+// LinkReport finds samples whose exercise or stretch is missing or points at
+// another sample, and exercises and stretches whose SampleID matches no
+// sample.
+func LinkReport(samples []datatypes.Sample, exercises []datatypes.Exercise, dynamics []datatypes.DynamicStr, statics []datatypes.StaticStr) datatypes.SampleLinkReport {
+	report := datatypes.SampleLinkReport{
+		OrphanedSamples: []datatypes.SampleLinkProblem{},
+		BrokenSampleIDs: []datatypes.SampleLinkProblem{},
+	}
+
+	backLinks := map[string]map[string]string{
+		"Exercise":        {},
+		"Dynamic Stretch": {},
+		"Static Stretch":  {},
+	}
+	for _, exer := range exercises {
+		backLinks["Exercise"][exer.BackendID] = exer.SampleID
+	}
+	for _, dynamic := range dynamics {
+		backLinks["Dynamic Stretch"][dynamic.BackendID] = dynamic.SampleID
+	}
+	for _, static := range statics {
+		backLinks["Static Stretch"][static.BackendID] = static.SampleID
+	}
+
+	sampleIDs := map[string]bool{}
+	for _, sample := range samples {
+		id := sample.ID.Hex()
+		sampleIDs[id] = true
+
+		problem := datatypes.SampleLinkProblem{SampleID: id, Type: sample.Type, ExOrStID: sample.ExOrStID}
+
+		links, ok := backLinks[sample.Type]
+		if !ok {
+			problem.Problem = "unknown sample type"
+			report.OrphanedSamples = append(report.OrphanedSamples, problem)
+			continue
+		}
+
+		backLink, ok := links[sample.ExOrStID]
+		if !ok {
+			problem.Problem = "linked " + sample.Type + " doesn't exist"
+			report.OrphanedSamples = append(report.OrphanedSamples, problem)
+		} else if backLink != id {
+			problem.Problem = "linked " + sample.Type + " has SampleID " + backLink
+			report.OrphanedSamples = append(report.OrphanedSamples, problem)
+		}
+	}
+
+	for _, sampleType := range SampleTypes {
+		for backendID, sampleID := range backLinks[sampleType] {
+			if sampleID != "" && !sampleIDs[sampleID] {
+				report.BrokenSampleIDs = append(report.BrokenSampleIDs, datatypes.SampleLinkProblem{
+					SampleID: sampleID,
+					Type:     sampleType,
+					ExOrStID: backendID,
+					Problem:  "SampleID matches no sample",
+				})
+			}
+		}
+	}
+
+	sortProblems(report.BrokenSampleIDs)
+
+	return report
+}
+
+func sortProblems(problems []datatypes.SampleLinkProblem) {
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Type != problems[j].Type {
+			return problems[i].Type < problems[j].Type
+		}
+		return problems[i].ExOrStID < problems[j].ExOrStID
+	})
+}
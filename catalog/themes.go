@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"fmt"
+	"i9-pos/datatypes"
+)
+
+// KnownImageSets collects every image set the catalog shows, plus extra ones
+// such as character poses that only themes use.
+func KnownImageSets(catalog datatypes.Catalog, extra []string) map[string]bool {
+	known := map[string]bool{}
+
+	add := func(ids ...string) {
+		for _, id := range ids {
+			if id != "" {
+				known[id] = true
+			}
+		}
+	}
+
+	add(extra...)
+
+	for _, exer := range catalog.Exercises {
+		add(exer.ImageSetID0)
+		for _, position := range append(exer.PositionSlice1, exer.PositionSlice2...) {
+			add(position.ImageSetID)
+		}
+	}
+
+	for _, dynamic := range catalog.Dynamics {
+		for _, position := range append(dynamic.PositionSlice1, dynamic.PositionSlice2...) {
+			add(position.ImageSetID)
+		}
+	}
+
+	for _, static := range catalog.Statics {
+		add(static.ImageSetID1, static.ImageSetID2)
+	}
+
+	for _, matrix := range catalog.Transitions {
+		for _, speed := range []*[11][11]datatypes.TransitionRep{&matrix.FastMatrix, &matrix.RegularMatrix, &matrix.SlowMatrix} {
+			for _, row := range speed {
+				for _, rep := range row {
+					add(rep.ImageSetIDs...)
+				}
+			}
+		}
+	}
+
+	return known
+}
+
+// ValidateTheme lists everything wrong with a theme before it is written.
+// Every position must be an image set the catalog knows.
+func ValidateTheme(theme datatypes.Theme, known map[string]bool) []string {
+	problems := []string{}
+
+	if theme.BackendID == "" {
+		problems = append(problems, "BackendID is required")
+	}
+
+	if theme.Name == "" {
+		problems = append(problems, "Name is required")
+	}
+
+	positions := []struct {
+		name string
+		id   string
+	}{
+		{"RestPosition", theme.RestPosition},
+		{"CongratsPosition", theme.CongratsPosition},
+		{"StandingPosition", theme.StandingPosition},
+	}
+
+	for _, position := range positions {
+		if position.id == "" {
+			problems = append(problems, position.name+" is required")
+		} else if !known[position.id] {
+			problems = append(problems, fmt.Sprintf("%s %q is not a known image set", position.name, position.id))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,187 @@
+package catalog
+
+import (
+	"fmt"
+	"i9-pos/datatypes"
+	"math"
+)
+
+// Allowed distance of a PercentSecs sum from 1
+const percentTolerance = 0.01
+
+// ValidateExercise lists everything wrong with an exercise before it is
+// written. PercentSecs of the non-hardcoded positions in each slice must sum
+// to 1 and the parent must be one of the transition matrix families.
+func ValidateExercise(exer datatypes.Exercise) []string {
+	problems := []string{}
+
+	if exer.BackendID == "" {
+		problems = append(problems, "BackendID is required")
+	}
+
+	if exer.Name == "" {
+		problems = append(problems, "Name is required")
+	}
+
+	if _, ok := datatypes.ParentMatIndex[exer.Parent]; !ok {
+		problems = append(problems, fmt.Sprintf("Parent %q is not a known family", exer.Parent))
+	}
+
+	if exer.MinSecs <= 0 || exer.MaxSecs <= 0 {
+		problems = append(problems, "MinSecs and MaxSecs must be positive")
+	}
+
+	if exer.MinSecs > exer.MaxSecs {
+		problems = append(problems, "MinSecs is greater than MaxSecs")
+	}
+
+	if exer.ImageSetID0 == "" {
+		problems = append(problems, "ImageSetID0 is required")
+	}
+
+	if len(exer.PositionSlice1) == 0 {
+		problems = append(problems, "PositionSlice1 needs at least one position")
+	}
+
+	problems = append(problems, exerPositionProblems("PositionSlice1", exer.PositionSlice1, exer.MinSecs)...)
+	problems = append(problems, exerPositionProblems("PositionSlice2", exer.PositionSlice2, exer.MinSecs)...)
+
+	return problems
+}
+
+// exerPositionProblems also checks that the hardcoded positions leave time
+// for the others, since a rep is never shorter than minSecs.
+func exerPositionProblems(name string, positions []datatypes.ExerPosition, minSecs float32) []string {
+	problems := []string{}
+
+	var sum, hardcodedSum float32
+	percentPositions := 0
+
+	for i, position := range positions {
+		if position.ImageSetID == "" {
+			problems = append(problems, fmt.Sprintf("%s[%d] has no ImageSetID", name, i))
+		}
+		if position.Hardcoded {
+			if position.HardcodedSecs <= 0 {
+				problems = append(problems, fmt.Sprintf("%s[%d] is hardcoded without HardcodedSecs", name, i))
+			}
+			hardcodedSum += position.HardcodedSecs
+			continue
+		}
+		sum += position.PercentSecs
+		percentPositions++
+	}
+
+	if percentPositions > 0 && !sumsToOne(sum) {
+		problems = append(problems, fmt.Sprintf("%s PercentSecs sum to %.3f, not 1", name, sum))
+	}
+
+	if hardcodedSum > minSecs {
+		problems = append(problems, fmt.Sprintf("%s HardcodedSecs sum to %.3f, more than MinSecs %.3f", name, hardcodedSum, minSecs))
+	}
+
+	return problems
+}
+
+func sumsToOne(sum float32) bool {
+	return math.Abs(float64(sum)-1) <= percentTolerance
+}
+
+// ValidateDynamic lists everything wrong with a dynamic stretch before it is
+// written. SeparateSets needs a second position slice to switch sides to.
+func ValidateDynamic(dynamic datatypes.DynamicStr) []string {
+	problems := []string{}
+
+	if dynamic.BackendID == "" {
+		problems = append(problems, "BackendID is required")
+	}
+
+	if dynamic.Name == "" {
+		problems = append(problems, "Name is required")
+	}
+
+	if dynamic.Secs <= 0 {
+		problems = append(problems, "Secs must be greater than 0")
+	}
+
+	if dynamic.SeparateSets && len(dynamic.PositionSlice2) == 0 {
+		problems = append(problems, "SeparateSets needs a non-empty PositionSlice2")
+	}
+
+	if len(dynamic.PositionSlice1) == 0 {
+		problems = append(problems, "PositionSlice1 needs at least one position")
+	}
+
+	problems = append(problems, strPositionProblems("PositionSlice1", dynamic.PositionSlice1)...)
+	problems = append(problems, strPositionProblems("PositionSlice2", dynamic.PositionSlice2)...)
+
+	return problems
+}
+
+// ValidateStatic lists everything wrong with a static stretch before it is
+// written.
+func ValidateStatic(static datatypes.StaticStr) []string {
+	problems := []string{}
+
+	if static.BackendID == "" {
+		problems = append(problems, "BackendID is required")
+	}
+
+	if static.Name == "" {
+		problems = append(problems, "Name is required")
+	}
+
+	if static.ImageSetID1 == "" {
+		problems = append(problems, "ImageSetID1 is required")
+	}
+
+	return problems
+}
+
+func strPositionProblems(name string, positions []datatypes.StrPosition) []string {
+	problems := []string{}
+
+	var sum float32
+	for i, position := range positions {
+		if position.ImageSetID == "" {
+			problems = append(problems, fmt.Sprintf("%s[%d] has no ImageSetID", name, i))
+		}
+		sum += position.PercentSecs
+	}
+
+	if len(positions) > 0 && !sumsToOne(sum) {
+		problems = append(problems, fmt.Sprintf("%s PercentSecs sum to %.3f, not 1", name, sum))
+	}
+
+	return problems
+}
+
+// ValidateTransitionRep lists everything wrong with one transition matrix
+// cell. Every image set needs a time and the times must add up to FullTime.
+func ValidateTransitionRep(rep datatypes.TransitionRep) []string {
+	problems := []string{}
+
+	if len(rep.ImageSetIDs) != len(rep.Times) {
+		problems = append(problems, fmt.Sprintf("%d ImageSetIDs but %d Times", len(rep.ImageSetIDs), len(rep.Times)))
+	}
+
+	var sum float32
+	for i, time := range rep.Times {
+		if time < 0 {
+			problems = append(problems, fmt.Sprintf("Times[%d] is negative", i))
+		}
+		sum += time
+	}
+
+	if math.Abs(float64(sum-rep.FullTime)) > percentTolerance {
+		problems = append(problems, fmt.Sprintf("Times sum to %.3f, not FullTime %.3f", sum, rep.FullTime))
+	}
+
+	for i, id := range rep.ImageSetIDs {
+		if id == "" {
+			problems = append(problems, fmt.Sprintf("ImageSetIDs[%d] is empty", i))
+		}
+	}
+
+	return problems
+}
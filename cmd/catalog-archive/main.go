@@ -0,0 +1,233 @@
+// Command catalog-archive exports the catalog collections to a versioned zip
+// of JSON files and imports such an archive into another database, upserting
+// by BackendID, samples by their exercise or stretch, and replacing the
+// transition matrix. It connects with MONGOSTRING.
+//
+// An import has to clear the server caches it makes stale. It clears the
+// bbolt cache file given by -cache, which a running server holds locked, and
+// sends DELETE /admin/cache to each -server with the bearer token in
+// CATALOG_ARCHIVE_TOKEN, which needs the cache:admin scope and, for another
+// tenant than its own, tenants:admin. Each server's cache is cleared once
+// before writing, so a token the servers refuse stops the import early. It
+// exits non-zero if any cache can't be cleared afterwards.
+//
+//	catalog-archive export -out catalog.zip
+//	catalog-archive import -in catalog.zip -dry-run
+//	catalog-archive import -in catalog.zip -cache "" -server https://pos.example.com
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func main() {
+
+	if len(os.Args) < 2 {
+		log.Fatal("Usage: catalog-archive export|import [flags]")
+	}
+
+	_ = godotenv.Load()
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	case "import":
+		importArchive(os.Args[2:])
+	default:
+		log.Fatalf("Unknown command %q, use export or import", os.Args[1])
+	}
+}
+
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("out", "catalog.zip", "archive to write")
+	tenant := flags.String("tenant", "", "tenant whose catalog to export, the default tenant if empty")
+	flags.Parse(args)
+
+	withDB(func(db *mongo.Database) {
+		archive, err := database.ExportArchive(db, *tenant, catalog.ArchiveVersion)
+		if err != nil {
+			log.Fatalf("Failed to read the catalog: %v", err)
+		}
+
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := catalog.WriteArchive(file, archive); err != nil {
+			file.Close()
+			log.Fatalf("Failed to write the archive: %v", err)
+		}
+
+		if err := file.Close(); err != nil {
+			log.Fatal(err)
+		}
+
+		for _, collection := range catalog.ArchiveCollections {
+			fmt.Printf("%s: %d\n", collection, archive.Manifest.Counts[collection])
+		}
+		fmt.Printf("Wrote %s\n", *out)
+	})
+}
+
+func importArchive(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	in := flags.String("in", "catalog.zip", "archive to read")
+	dryRun := flags.Bool("dry-run", false, "only report what would change")
+	format := flags.String("format", "text", "output format, text or json")
+	tenant := flags.String("tenant", "", "tenant whose catalog to import into, the default tenant if empty")
+	cachePath := flags.String("cache", "cache.db", "bbolt cache file to clear after the import, empty for none")
+	servers := flags.String("server", "", "comma separated server URLs to send DELETE /admin/cache after the import")
+	flags.Parse(args)
+
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown format %q, use text or json", *format)
+	}
+
+	archive, err := catalog.ReadArchive(*in)
+	if err != nil {
+		log.Fatalf("Failed to read the archive: %v", err)
+	}
+
+	// Everything needed to clear the caches is checked before importing, so
+	// a change can't be left behind stale caches.
+	var boltDB *bbolt.DB
+	serverURLs := splitList(*servers)
+	token := os.Getenv("CATALOG_ARCHIVE_TOKEN")
+
+	if !*dryRun {
+		if *cachePath == "" && len(serverURLs) == 0 {
+			log.Fatal("An import must clear the server caches, pass -cache, -server or both")
+		}
+
+		if *cachePath != "" {
+			boltDB, err = bbolt.Open(*cachePath, 0666, &bbolt.Options{Timeout: time.Second})
+			if err != nil {
+				log.Fatalf("Failed to open the cache %s, if a running server holds it pass -cache \"\" -server <url>: %v", *cachePath, err)
+			}
+			defer boltDB.Close()
+		}
+
+		if len(serverURLs) > 0 && token == "" {
+			log.Fatal("CATALOG_ARCHIVE_TOKEN must hold a token for DELETE /admin/cache")
+		}
+
+		for _, server := range serverURLs {
+			if err := clearServerCache(server, token, *tenant); err != nil {
+				log.Fatalf("Nothing imported, the cache of %s can't be cleared: %v", server, err)
+			}
+		}
+	}
+
+	withDB(func(db *mongo.Database) {
+		report, err := database.ImportArchive(db, boltDB, *tenant, archive, *dryRun)
+		if err != nil {
+			log.Fatalf("Failed to import the archive: %v", err)
+		}
+
+		if *format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			writeText(report)
+		}
+
+		if *dryRun || len(report.Changes) == 0 {
+			return
+		}
+
+		failed := 0
+		for _, server := range serverURLs {
+			if err := clearServerCache(server, token, *tenant); err != nil {
+				log.Printf("Catalog changed but the cache of %s wasn't cleared, send it DELETE /admin/cache: %v", server, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			log.Fatalf("%d of %d server caches weren't cleared", failed, len(serverURLs))
+		}
+	})
+}
+
+// clearServerCache sends DELETE /admin/cache to a running server.
+func clearServerCache(server, token, tenant string) error {
+	req, err := http.NewRequest(http.MethodDelete, strings.TrimSuffix(server, "/")+"/admin/cache", nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("status %s: %s", resp.Status, body.Error.Message)
+	}
+	return nil
+}
+
+func splitList(list string) []string {
+	ret := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func withDB(fn func(db *mongo.Database)) {
+	client, db, err := database.ConnectDB()
+	if err != nil {
+		log.Fatalf("Error while connecting to mongoDB: %v", err)
+	}
+	defer database.DisConnectDB(client)
+
+	fn(db)
+}
+
+func writeText(report datatypes.ImportReport) {
+	for _, change := range report.Changes {
+		if change.Action == "update" {
+			fmt.Printf("update %s %s: %v\n", change.Collection, change.ID, change.Fields)
+		} else {
+			fmt.Printf("%s %s %s\n", change.Action, change.Collection, change.ID)
+		}
+	}
+
+	for _, collection := range catalog.ArchiveCollections {
+		fmt.Printf("%s: %d unchanged\n", collection, report.Unchanged[collection])
+	}
+}
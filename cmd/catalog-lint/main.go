@@ -0,0 +1,86 @@
+// Command catalog-lint checks the exercise and stretch catalog for problems
+// that would break workout generation. It reads the catalog from MONGOSTRING,
+// or from a catalog-archive export with -file, and exits 1 when it finds any
+// errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"i9-pos/catalog"
+	"i9-pos/database"
+	"i9-pos/datatypes"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+
+	file := flag.String("file", "", "lint a catalog-archive export instead of the database")
+	format := flag.String("format", "text", "output format, text or json")
+	tenant := flag.String("tenant", "", "tenant whose catalog to lint, the default tenant if empty")
+	flag.Parse()
+
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Unknown format %q, use text or json", *format)
+	}
+
+	cat, err := loadCatalog(*file, *tenant)
+	if err != nil {
+		log.Fatalf("Failed to load the catalog: %v", err)
+	}
+
+	problems := catalog.Lint(cat, database.ThemeImageSets(database.DefaultTenantSettings(*tenant)))
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(problems); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		writeText(problems)
+	}
+
+	if catalog.HasErrors(problems) {
+		os.Exit(1)
+	}
+}
+
+func loadCatalog(file, tenant string) (datatypes.Catalog, error) {
+	if file != "" {
+		archive, err := catalog.ReadArchive(file)
+		if err != nil {
+			return datatypes.Catalog{}, err
+		}
+		return catalog.ArchiveCatalog(archive)
+	}
+
+	_ = godotenv.Load()
+
+	client, db, err := database.ConnectDB()
+	if err != nil {
+		return datatypes.Catalog{}, err
+	}
+	defer database.DisConnectDB(client)
+
+	return database.LoadCatalog(db, tenant)
+}
+
+func writeText(problems []datatypes.LintProblem) {
+	errors, warnings := 0, 0
+
+	for _, problem := range problems {
+		fmt.Printf("%s: %s %s: %s\n", problem.Severity, problem.Collection, problem.BackendID, problem.Problem)
+		if problem.Severity == catalog.SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+
+	fmt.Printf("%d errors, %d warnings\n", errors, warnings)
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"i9-pos/datatypes"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LoadCatalog reads every catalog collection straight from the DB,
+// skipping the cache.
+func LoadCatalog(database *mongo.Database, tenant string) (datatypes.Catalog, error) {
+	var catalog datatypes.Catalog
+	var err error
+
+	if catalog.Exercises, err = AllExercises(database, tenant); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Dynamics, err = AllDynamics(database, tenant); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Statics, err = AllStatics(database, tenant); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Samples, err = AllSamples(database, tenant); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Transitions, err = findAll[datatypes.TransitionMatrix](database, tenant, TransitionCollection); err != nil {
+		return catalog, err
+	}
+
+	if catalog.Themes, err = AllThemes(database, tenant); err != nil {
+		return catalog, err
+	}
+
+	return catalog, nil
+}
@@ -0,0 +1,276 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"i9-pos/datatypes"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Catalog collections and the fields an import matches them by. Samples have
+// no BackendID and their _id differs between databases, so they match on the
+// exercise or stretch they belong to. They come first so the SampleIDs of the
+// exercises and stretches can follow them. The transition matrix has no key,
+// there is one per tenant and the archive's replaces it.
+var archiveKeys = []struct {
+	Collection string
+	Keys       []string
+}{
+	{SampleCollection, []string{"type", "exorstid"}},
+	{ExerciseCollection, []string{"backendID"}},
+	{DynamicCollection, []string{"backendID"}},
+	{StaticCollection, []string{"backendID"}},
+	{TransitionCollection, nil},
+	{ThemeCollection, []string{"backendID"}},
+}
+
+// ExportArchive reads every catalog collection as raw documents.
+func ExportArchive(database *mongo.Database, tenant string, version int) (datatypes.CatalogArchive, error) {
+	archive := datatypes.CatalogArchive{
+		Manifest: datatypes.CatalogManifest{
+			Version:    version,
+			ExportedAt: time.Now().UTC(),
+			Database:   database.Name(),
+			Tenant:     tenant,
+			Counts:     map[string]int{},
+		},
+		Collections: map[string][]bson.D{},
+	}
+
+	for _, archiveKey := range archiveKeys {
+		docs, err := findAll[bson.D](database, tenant, archiveKey.Collection)
+		if err != nil {
+			return archive, err
+		}
+
+		archive.Collections[archiveKey.Collection] = docs
+		archive.Manifest.Counts[archiveKey.Collection] = len(docs)
+	}
+
+	return archive, nil
+}
+
+// ImportArchive upserts an archive into the tenant's collections and reports
+// what was created or updated. Documents only in the DB are left alone. With dryRun nothing is
+// written and the report shows what an import would change.
+//
+// After a change the tenant's keys in boltDB are cleared. boltDB is nil when
+// the caller clears the caches another way, and servers with their own cache
+// keep serving the old catalog until DELETE /admin/cache.
+func ImportArchive(database *mongo.Database, boltDB *bbolt.DB, tenant string, archive datatypes.CatalogArchive, dryRun bool) (datatypes.ImportReport, error) {
+	report := datatypes.ImportReport{
+		Changes:   []datatypes.CatalogChange{},
+		Unchanged: map[string]int{},
+	}
+
+	// Archive sample _id to the _id the sample has, or gets, in the target.
+	sampleIDs := map[string]string{}
+
+	for _, archiveKey := range archiveKeys {
+		collection := Collection(database, tenant, archiveKey.Collection)
+
+		existing, err := findAll[bson.D](database, tenant, archiveKey.Collection)
+		if err != nil {
+			return report, err
+		}
+
+		byKey := map[string]bson.D{}
+		takenIDs := map[string]bool{}
+		for _, doc := range existing {
+			if key, ok := docKey(doc, archiveKey.Keys); ok {
+				byKey[key] = doc
+			}
+			if id, ok := docKey(doc, []string{"_id"}); ok {
+				takenIDs[id] = true
+			}
+		}
+
+		report.Unchanged[archiveKey.Collection] = 0
+
+		for i, doc := range archive.Collections[archiveKey.Collection] {
+			key, ok := docKey(doc, archiveKey.Keys)
+			if !ok {
+				return report, fmt.Errorf("%s[%d] has no %s", archiveKey.Collection, i, strings.Join(archiveKey.Keys, " and "))
+			}
+
+			change := datatypes.CatalogChange{Collection: archiveKey.Collection, ID: key}
+
+			// A match may have a different _id in the target, which Mongo
+			// won't let a replace change.
+			incoming := withoutField(remapSampleID(doc, sampleIDs), "_id")
+			archiveID, _ := docKey(doc, []string{"_id"})
+
+			current, found := byKey[key]
+			if found {
+				if archiveKey.Collection == SampleCollection {
+					sampleIDs[archiveID], _ = docKey(current, []string{"_id"})
+				}
+
+				fields, err := changedFields(withoutField(current, "_id"), incoming)
+				if err != nil {
+					return report, err
+				}
+				if len(fields) == 0 {
+					report.Unchanged[archiveKey.Collection]++
+					continue
+				}
+
+				change.Action = "update"
+				change.Fields = fields
+			} else {
+				change.Action = "create"
+
+				// New samples keep their _id, unless the target already
+				// uses it, so the SampleIDs pointing at them still match.
+				if archiveKey.Collection == SampleCollection {
+					id, isObjectID := docValue(doc, "_id").(primitive.ObjectID)
+					if !isObjectID || takenIDs[archiveID] {
+						id = primitive.NewObjectID()
+					}
+					sampleIDs[archiveID] = id.Hex()
+					incoming = append(bson.D{{Key: "_id", Value: id}}, incoming...)
+				}
+			}
+
+			report.Changes = append(report.Changes, change)
+
+			if dryRun {
+				continue
+			}
+
+			if found {
+				_, err = collection.ReplaceOne(context.Background(), bson.M{"_id": docValue(current, "_id")}, incoming)
+			} else {
+				_, err = collection.InsertOne(context.Background(), incoming)
+			}
+			if err != nil {
+				return report, fmt.Errorf("%s %s %s: %w", change.Action, archiveKey.Collection, key, err)
+			}
+		}
+	}
+
+	if !dryRun && len(report.Changes) > 0 {
+		if err := incCatalogVersion(database, tenant); err != nil {
+			return report, err
+		}
+		if boltDB != nil {
+			if err := ClearTenantCache(boltDB, tenant); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func docValue(doc bson.D, field string) interface{} {
+	for _, elem := range doc {
+		if elem.Key == field {
+			return elem.Value
+		}
+	}
+	return nil
+}
+
+// docKey joins the values of the key fields, each of which must be set. A
+// collection without key fields holds a single document.
+func docKey(doc bson.D, fields []string) (string, bool) {
+	if len(fields) == 0 {
+		return "matrix", true
+	}
+
+	values := []string{}
+	for _, field := range fields {
+		switch value := docValue(doc, field).(type) {
+		case string:
+			if value == "" {
+				return "", false
+			}
+			values = append(values, value)
+		case primitive.ObjectID:
+			values = append(values, value.Hex())
+		default:
+			return "", false
+		}
+	}
+
+	return strings.Join(values, "/"), true
+}
+
+// remapSampleID points an exercise or stretch at the _id its sample has in
+// the target.
+func remapSampleID(doc bson.D, sampleIDs map[string]string) bson.D {
+	sampleID, ok := docValue(doc, "sampleid").(string)
+	if !ok || sampleIDs[sampleID] == "" || sampleIDs[sampleID] == sampleID {
+		return doc
+	}
+
+	ret := bson.D{}
+	for _, elem := range doc {
+		if elem.Key == "sampleid" {
+			elem.Value = sampleIDs[sampleID]
+		}
+		ret = append(ret, elem)
+	}
+	return ret
+}
+
+func withoutField(doc bson.D, field string) bson.D {
+	ret := bson.D{}
+	for _, elem := range doc {
+		if elem.Key != field {
+			ret = append(ret, elem)
+		}
+	}
+	return ret
+}
+
+// changedFields lists the top level fields whose BSON encoding differs,
+// including fields only one side has.
+func changedFields(current, incoming bson.D) ([]string, error) {
+	encode := func(doc bson.D) (map[string][]byte, error) {
+		encoded := map[string][]byte{}
+		for _, elem := range doc {
+			data, err := bson.Marshal(bson.D{{Key: "v", Value: elem.Value}})
+			if err != nil {
+				return nil, err
+			}
+			encoded[elem.Key] = data
+		}
+		return encoded, nil
+	}
+
+	before, err := encode(current)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := encode(incoming)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for field, data := range after {
+		if !bytes.Equal(before[field], data) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+
+	return fields, nil
+}
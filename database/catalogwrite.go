@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"i9-pos/datatypes"
+	"i9-pos/metrics"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrDuplicateBackendID = errors.New("a document with this backendID already exists")
+
+// ClearCacheKey drops one tenant's cached collection so the next read
+// refills it from MongoDB.
+func ClearCacheKey(boltDB *bbolt.DB, tenant, key string) error {
+	return boltDB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return nil
+		}
+		return b.Delete(CacheKey(tenant, key))
+	})
+}
+
+// Keys a tenant's catalog and settings are cached under.
+var tenantCacheKeys = []string{"Exercise", "Dynamic", "Static", "Sample", "Transition", "Theme", "Tenant"}
+
+// ClearTenantCache drops everything cached for one tenant.
+func ClearTenantCache(boltDB *bbolt.DB, tenant string) error {
+	return boltDB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return nil
+		}
+		for _, key := range tenantCacheKeys {
+			if err := b.Delete(CacheKey(tenant, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func AllExercises(database *mongo.Database, tenant string) ([]datatypes.Exercise, error) {
+	return findAll[datatypes.Exercise](database, tenant, ExerciseCollection)
+}
+
+func ExerciseByBackendID(database *mongo.Database, tenant, id string) (datatypes.Exercise, error) {
+	return findByBackendID[datatypes.Exercise](database, tenant, ExerciseCollection, id)
+}
+
+func InsertExercise(database *mongo.Database, boltDB *bbolt.DB, tenant string, exer datatypes.Exercise) (datatypes.Exercise, error) {
+	exer.ID = primitive.NilObjectID
+
+	if exer.SampleID != "" {
+		if err := checkSampleID(database, tenant, "Exercise", exer.BackendID, exer.SampleID); err != nil {
+			return datatypes.Exercise{}, err
+		}
+	}
+
+	id, err := insertDoc(database, boltDB, tenant, ExerciseCollection, "Exercise", exer)
+	if err != nil {
+		return datatypes.Exercise{}, err
+	}
+
+	exer.ID = id
+	return exer, nil
+}
+
+func ReplaceExercise(database *mongo.Database, boltDB *bbolt.DB, tenant string, exer datatypes.Exercise) (datatypes.Exercise, error) {
+	existing, err := ExerciseByBackendID(database, tenant, exer.BackendID)
+	if err != nil {
+		return datatypes.Exercise{}, err
+	}
+
+	exer.ID = existing.ID
+
+	if exer.SampleID != existing.SampleID {
+		if err := checkSampleID(database, tenant, "Exercise", exer.BackendID, exer.SampleID); err != nil {
+			return datatypes.Exercise{}, err
+		}
+	}
+
+	if err := replaceByBackendID(database, boltDB, tenant, ExerciseCollection, "Exercise", exer.BackendID, exer); err != nil {
+		return datatypes.Exercise{}, err
+	}
+
+	return exer, nil
+}
+
+// DeleteExercise refuses while a sample still links to the exercise.
+func DeleteExercise(database *mongo.Database, boltDB *bbolt.DB, tenant, id string) error {
+	if err := checkNoSample(database, tenant, "Exercise", id); err != nil {
+		return err
+	}
+
+	return deleteByBackendID(database, boltDB, tenant, ExerciseCollection, "Exercise", id)
+}
+
+func findAll[T any](database *mongo.Database, tenant, collection string) ([]T, error) {
+	defer metrics.MongoFetch(collection, time.Now())
+
+	docs := []T{}
+
+	cursor, err := Collection(database, tenant, collection).Find(context.Background(), bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	if err = cursor.All(context.Background(), &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func findByBackendID[T any](database *mongo.Database, tenant, collection, id string) (T, error) {
+	defer metrics.MongoFetch(collection, time.Now())
+
+	var doc T
+
+	err := Collection(database, tenant, collection).FindOne(context.Background(), bson.M{"backendID": id}).Decode(&doc)
+
+	return doc, err
+}
+
+// insertDoc relies on the unique backendID index EnsureIndexes creates to
+// refuse a backendID that's taken.
+func insertDoc(database *mongo.Database, boltDB *bbolt.DB, tenant, collection, cacheKey string, doc interface{}) (primitive.ObjectID, error) {
+	result, err := Collection(database, tenant, collection).InsertOne(context.Background(), doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return primitive.NilObjectID, ErrDuplicateBackendID
+	}
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	if err := catalogChanged(database, boltDB, tenant, cacheKey); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, _ := result.InsertedID.(primitive.ObjectID)
+	return id, nil
+}
+
+func replaceByBackendID(database *mongo.Database, boltDB *bbolt.DB, tenant, collection, cacheKey, backendID string, doc interface{}) error {
+	result, err := Collection(database, tenant, collection).ReplaceOne(context.Background(), bson.M{"backendID": backendID}, doc)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return catalogChanged(database, boltDB, tenant, cacheKey)
+}
+
+func deleteByBackendID(database *mongo.Database, boltDB *bbolt.DB, tenant, collection, cacheKey, backendID string) error {
+	result, err := Collection(database, tenant, collection).DeleteOne(context.Background(), bson.M{"backendID": backendID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return catalogChanged(database, boltDB, tenant, cacheKey)
+}
+
+func AllDynamics(database *mongo.Database, tenant string) ([]datatypes.DynamicStr, error) {
+	return findAll[datatypes.DynamicStr](database, tenant, DynamicCollection)
+}
+
+func DynamicByBackendID(database *mongo.Database, tenant, id string) (datatypes.DynamicStr, error) {
+	return findByBackendID[datatypes.DynamicStr](database, tenant, DynamicCollection, id)
+}
+
+func InsertDynamic(database *mongo.Database, boltDB *bbolt.DB, tenant string, dynamic datatypes.DynamicStr) (datatypes.DynamicStr, error) {
+	dynamic.ID = primitive.NilObjectID
+
+	if dynamic.SampleID != "" {
+		if err := checkSampleID(database, tenant, "Dynamic Stretch", dynamic.BackendID, dynamic.SampleID); err != nil {
+			return datatypes.DynamicStr{}, err
+		}
+	}
+
+	id, err := insertDoc(database, boltDB, tenant, DynamicCollection, "Dynamic", dynamic)
+	if err != nil {
+		return datatypes.DynamicStr{}, err
+	}
+
+	dynamic.ID = id
+	return dynamic, nil
+}
+
+func ReplaceDynamic(database *mongo.Database, boltDB *bbolt.DB, tenant string, dynamic datatypes.DynamicStr) (datatypes.DynamicStr, error) {
+	existing, err := DynamicByBackendID(database, tenant, dynamic.BackendID)
+	if err != nil {
+		return datatypes.DynamicStr{}, err
+	}
+
+	dynamic.ID = existing.ID
+
+	if dynamic.SampleID != existing.SampleID {
+		if err := checkSampleID(database, tenant, "Dynamic Stretch", dynamic.BackendID, dynamic.SampleID); err != nil {
+			return datatypes.DynamicStr{}, err
+		}
+	}
+
+	if err := replaceByBackendID(database, boltDB, tenant, DynamicCollection, "Dynamic", dynamic.BackendID, dynamic); err != nil {
+		return datatypes.DynamicStr{}, err
+	}
+
+	return dynamic, nil
+}
+
+// DeleteDynamic refuses while a sample still links to the stretch.
+func DeleteDynamic(database *mongo.Database, boltDB *bbolt.DB, tenant, id string) error {
+	if err := checkNoSample(database, tenant, "Dynamic Stretch", id); err != nil {
+		return err
+	}
+
+	return deleteByBackendID(database, boltDB, tenant, DynamicCollection, "Dynamic", id)
+}
+
+func AllStatics(database *mongo.Database, tenant string) ([]datatypes.StaticStr, error) {
+	return findAll[datatypes.StaticStr](database, tenant, StaticCollection)
+}
+
+func StaticByBackendID(database *mongo.Database, tenant, id string) (datatypes.StaticStr, error) {
+	return findByBackendID[datatypes.StaticStr](database, tenant, StaticCollection, id)
+}
+
+func InsertStatic(database *mongo.Database, boltDB *bbolt.DB, tenant string, static datatypes.StaticStr) (datatypes.StaticStr, error) {
+	static.ID = primitive.NilObjectID
+
+	if static.SampleID != "" {
+		if err := checkSampleID(database, tenant, "Static Stretch", static.BackendID, static.SampleID); err != nil {
+			return datatypes.StaticStr{}, err
+		}
+	}
+
+	id, err := insertDoc(database, boltDB, tenant, StaticCollection, "Static", static)
+	if err != nil {
+		return datatypes.StaticStr{}, err
+	}
+
+	static.ID = id
+	return static, nil
+}
+
+func ReplaceStatic(database *mongo.Database, boltDB *bbolt.DB, tenant string, static datatypes.StaticStr) (datatypes.StaticStr, error) {
+	existing, err := StaticByBackendID(database, tenant, static.BackendID)
+	if err != nil {
+		return datatypes.StaticStr{}, err
+	}
+
+	static.ID = existing.ID
+
+	if static.SampleID != existing.SampleID {
+		if err := checkSampleID(database, tenant, "Static Stretch", static.BackendID, static.SampleID); err != nil {
+			return datatypes.StaticStr{}, err
+		}
+	}
+
+	if err := replaceByBackendID(database, boltDB, tenant, StaticCollection, "Static", static.BackendID, static); err != nil {
+		return datatypes.StaticStr{}, err
+	}
+
+	return static, nil
+}
+
+// DeleteStatic refuses while a sample still links to the stretch.
+func DeleteStatic(database *mongo.Database, boltDB *bbolt.DB, tenant, id string) error {
+	if err := checkNoSample(database, tenant, "Static Stretch", id); err != nil {
+		return err
+	}
+
+	return deleteByBackendID(database, boltDB, tenant, StaticCollection, "Static", id)
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Logical collection names. The real names default to these and can be
+// changed with MONGO_COLLECTIONS, or per tenant with TENANT_COLLECTIONS. A
+// tenant's unmapped collections are prefixed with the tenant ID, never shared
+// with the default tenant.
+const (
+	ExerciseCollection          = "exercise"
+	DynamicCollection           = "dynamicstretch"
+	StaticCollection            = "staticstretch"
+	SampleCollection            = "sample"
+	TransitionCollection        = "transition"
+	TransitionHistoryCollection = "transitionhistory"
+	TenantCollection            = "tenant"
+	ThemeCollection             = "theme"
+)
+
+// Tenant whose collections are used when none is given
+const DefaultTenant = ""
+
+var knownCollections = []string{
+	ExerciseCollection,
+	DynamicCollection,
+	StaticCollection,
+	SampleCollection,
+	TransitionCollection,
+	TransitionHistoryCollection,
+	TenantCollection,
+	ThemeCollection,
+}
+
+// Real collection names by tenant and logical name, filled in by ConnectDB
+var collectionNames = map[string]map[string]string{}
+
+// DatabaseName reads MONGO_DATABASE, defaulting to i9pos.
+func DatabaseName() string {
+	if name := os.Getenv("MONGO_DATABASE"); name != "" {
+		return name
+	}
+	return "i9pos"
+}
+
+// loadCollectionNames reads MONGO_COLLECTIONS as "exercise=stg_exercise;..."
+// and TENANT_COLLECTIONS as "partner:exercise=partner_exercise;...".
+// Tenant entries win over MONGO_COLLECTIONS.
+func loadCollectionNames() (map[string]map[string]string, error) {
+	names := map[string]map[string]string{DefaultTenant: {}}
+
+	for _, entry := range envEntries("MONGO_COLLECTIONS") {
+		name, real, err := parseCollectionEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MONGO_COLLECTIONS entry %q: %w", entry, err)
+		}
+		names[DefaultTenant][name] = real
+	}
+
+	for _, entry := range envEntries("TENANT_COLLECTIONS") {
+		tenant, rest, found := strings.Cut(entry, ":")
+		tenant = strings.TrimSpace(tenant)
+		if !found || tenant == "" {
+			return nil, fmt.Errorf("invalid TENANT_COLLECTIONS entry %q: missing tenant", entry)
+		}
+
+		name, real, err := parseCollectionEntry(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TENANT_COLLECTIONS entry %q: %w", entry, err)
+		}
+
+		if names[tenant] == nil {
+			names[tenant] = map[string]string{}
+		}
+		names[tenant][name] = real
+	}
+
+	return names, nil
+}
+
+func envEntries(key string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func parseCollectionEntry(entry string) (string, string, error) {
+	name, real, found := strings.Cut(entry, "=")
+	name, real = strings.TrimSpace(name), strings.TrimSpace(real)
+	if !found || real == "" {
+		return "", "", fmt.Errorf("entries must look like exercise=stg_exercise")
+	}
+
+	for _, known := range knownCollections {
+		if name == known {
+			return name, real, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("unknown collection %q", name)
+}
+
+// Tenants reads TENANTS, a comma separated list of tenant IDs, after the
+// default tenant.
+func Tenants() []string {
+	tenants := []string{DefaultTenant}
+	for _, tenant := range strings.Split(os.Getenv("TENANTS"), ",") {
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			tenants = append(tenants, tenant)
+		}
+	}
+	return tenants
+}
+
+// checkTenants refuses TENANT_COLLECTIONS entries for tenants TENANTS
+// doesn't list, and logs the prefixed names the listed tenants fall back to.
+func checkTenants(names map[string]map[string]string, tenants []string) error {
+	known := map[string]bool{}
+	for _, tenant := range tenants {
+		known[tenant] = true
+	}
+
+	for tenant := range names {
+		if !known[tenant] {
+			return fmt.Errorf("TENANT_COLLECTIONS maps tenant %q, which TENANTS doesn't list", tenant)
+		}
+	}
+
+	for _, tenant := range tenants[1:] {
+		prefixed := []string{}
+		for _, name := range knownCollections {
+			if _, ok := names[tenant][name]; !ok && name != TenantCollection {
+				prefixed = append(prefixed, prefixedName(tenant, name))
+			}
+		}
+		if len(prefixed) > 0 {
+			log.Printf("Tenant %q has no TENANT_COLLECTIONS entry for some collections, using %v", tenant, prefixed)
+		}
+	}
+
+	return nil
+}
+
+// CollectionName maps a logical collection name to the real one for a
+// tenant.
+func CollectionName(tenant, name string) string {
+	if real, ok := collectionNames[tenant][name]; ok {
+		return real
+	}
+	if tenant != DefaultTenant {
+		return prefixedName(tenant, name)
+	}
+	return name
+}
+
+// prefixedName is the collection a tenant uses when TENANT_COLLECTIONS
+// doesn't map one, built from the default tenant's real name.
+func prefixedName(tenant, name string) string {
+	return tenant + "_" + CollectionName(DefaultTenant, name)
+}
+
+// Collection returns the tenant's real collection behind a logical name.
+func Collection(database *mongo.Database, tenant, name string) *mongo.Collection {
+	return database.Collection(CollectionName(tenant, name))
+}
@@ -19,6 +19,18 @@ func DisConnectDB(client *mongo.Client) {
 
 func ConnectDB() (*mongo.Client, *mongo.Database, error) {
 
+	names, err := loadCollectionNames()
+	if err != nil {
+		log.Fatal(err)
+		return nil, nil, err
+	}
+	collectionNames = names
+
+	if err := checkTenants(names, Tenants()); err != nil {
+		log.Fatal(err)
+		return nil, nil, err
+	}
+
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
 	connectStr := os.Getenv("MONGOSTRING")
 	clientOptions := options.Client().ApplyURI(connectStr).SetServerAPIOptions(serverAPI)
@@ -37,8 +49,8 @@ func ConnectDB() (*mongo.Client, *mongo.Database, error) {
 		return nil, nil, err
 	}
 
-	// Specify the database and collection
-	database := client.Database("i9pos")
+	// Specify the database, collections are resolved per query
+	database := client.Database(DatabaseName())
 
 	return client, database, nil
 }
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections whose documents are addressed by a unique backendID
+var backendIDCollections = []string{ExerciseCollection, DynamicCollection, StaticCollection, ThemeCollection}
+
+// EnsureIndexes creates the unique backendID index on the tenants' catalog
+// collections, so concurrent inserts can't create duplicates. Documents
+// without a backendID are left out of the index. Creating an index that
+// already exists does nothing.
+func EnsureIndexes(database *mongo.Database, tenants []string) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "backendID", Value: 1}},
+		Options: options.Index().
+			SetName("backendID_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"backendID": bson.M{"$type": "string"}}),
+	}
+
+	for _, tenant := range tenants {
+		for _, collection := range backendIDCollections {
+			if _, err := Collection(database, tenant, collection).Indexes().CreateOne(context.Background(), index); err != nil {
+				return fmt.Errorf("unique backendID index on %s: %w, run catalog-lint to find duplicates", CollectionName(tenant, collection), err)
+			}
+		}
+	}
+
+	return nil
+}
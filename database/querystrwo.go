@@ -1,20 +1,19 @@
 package database
 
 import (
-	"context"
 	"encoding/json"
 	"i9-pos/datatypes"
+	"i9-pos/metrics"
 	"log"
 	"slices"
 	"sync"
 
 	"github.com/hashicorp/go-multierror"
 	"go.etcd.io/bbolt"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func QueryStretchWO(database *mongo.Database, boltDB *bbolt.DB, statics, dynamics []string) (map[string]datatypes.DynamicStr, map[string]datatypes.StaticStr, error) {
+func QueryStretchWO(database *mongo.Database, boltDB *bbolt.DB, tenant string, statics, dynamics []string) (map[string]datatypes.DynamicStr, map[string]datatypes.StaticStr, error) {
 	var wg sync.WaitGroup
 
 	errChan := make(chan error, 2)
@@ -25,7 +24,7 @@ func QueryStretchWO(database *mongo.Database, boltDB *bbolt.DB, statics, dynamic
 	go func() {
 		defer wg.Done()
 		var err error
-		dynamicStr, err = GetDynamics(database, boltDB, dynamics)
+		dynamicStr, err = GetDynamics(database, boltDB, tenant, dynamics)
 		if err != nil {
 			errChan <- err
 		}
@@ -35,7 +34,7 @@ func QueryStretchWO(database *mongo.Database, boltDB *bbolt.DB, statics, dynamic
 	go func() {
 		defer wg.Done()
 		var err error
-		staticStr, err = GetStatics(database, boltDB, statics)
+		staticStr, err = GetStatics(database, boltDB, tenant, statics)
 		if err != nil {
 			errChan <- err
 		}
@@ -59,7 +58,7 @@ func QueryStretchWO(database *mongo.Database, boltDB *bbolt.DB, statics, dynamic
 	return dynamicStr, staticStr, nil
 }
 
-func GetDynamics(database *mongo.Database, boltDB *bbolt.DB, dynamics []string) (map[string]datatypes.DynamicStr, error) {
+func GetDynamics(database *mongo.Database, boltDB *bbolt.DB, tenant string, dynamics []string) (map[string]datatypes.DynamicStr, error) {
 
 	dynamicStr := map[string]datatypes.DynamicStr{}
 
@@ -71,22 +70,20 @@ func GetDynamics(database *mongo.Database, boltDB *bbolt.DB, dynamics []string)
 			return err
 		}
 
-		v := b.Get([]byte("Dynamic"))
+		v := b.Get(CacheKey(tenant, "Dynamic"))
 		if v != nil {
 			err := json.Unmarshal(v, &dynamicList)
 			if err == nil {
+				metrics.CacheHit("Dynamic")
 				return nil
 			}
 			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
 		}
 
-		cursor, err := database.Collection("dynamicstretch").Find(context.Background(), bson.D{})
-		if err != nil {
-			return err
-		}
-		defer cursor.Close(context.Background())
+		metrics.CacheMiss("Dynamic")
 
-		if err = cursor.All(context.Background(), &dynamicList); err != nil {
+		dynamicList, err = findAll[datatypes.DynamicStr](database, tenant, DynamicCollection)
+		if err != nil {
 			return err
 		}
 
@@ -95,11 +92,13 @@ func GetDynamics(database *mongo.Database, boltDB *bbolt.DB, dynamics []string)
 			return err
 		}
 
-		err = b.Put([]byte("Dynamic"), data)
+		err = b.Put(CacheKey(tenant, "Dynamic"), data)
 		if err != nil {
 			return err
 		}
 
+		metrics.CacheRefill("Dynamic")
+
 		return nil
 	})
 
@@ -116,7 +115,7 @@ func GetDynamics(database *mongo.Database, boltDB *bbolt.DB, dynamics []string)
 	return dynamicStr, nil
 }
 
-func GetStatics(database *mongo.Database, boltDB *bbolt.DB, statics []string) (map[string]datatypes.StaticStr, error) {
+func GetStatics(database *mongo.Database, boltDB *bbolt.DB, tenant string, statics []string) (map[string]datatypes.StaticStr, error) {
 	staticStr := map[string]datatypes.StaticStr{}
 
 	var staticList []datatypes.StaticStr
@@ -127,22 +126,20 @@ func GetStatics(database *mongo.Database, boltDB *bbolt.DB, statics []string) (m
 			return err
 		}
 
-		v := b.Get([]byte("Static"))
+		v := b.Get(CacheKey(tenant, "Static"))
 		if v != nil {
 			err := json.Unmarshal(v, &staticList)
 			if err == nil {
+				metrics.CacheHit("Static")
 				return nil
 			}
 			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
 		}
 
-		cursor, err := database.Collection("staticstretch").Find(context.Background(), bson.D{})
-		if err != nil {
-			return err
-		}
-		defer cursor.Close(context.Background())
+		metrics.CacheMiss("Static")
 
-		if err = cursor.All(context.Background(), &staticList); err != nil {
+		staticList, err = findAll[datatypes.StaticStr](database, tenant, StaticCollection)
+		if err != nil {
 			return err
 		}
 
@@ -151,11 +148,13 @@ func GetStatics(database *mongo.Database, boltDB *bbolt.DB, statics []string) (m
 			return err
 		}
 
-		err = b.Put([]byte("Static"), data)
+		err = b.Put(CacheKey(tenant, "Static"), data)
 		if err != nil {
 			return err
 		}
 
+		metrics.CacheRefill("Static")
+
 		return nil
 	})
 
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"i9-pos/datatypes"
+	"i9-pos/metrics"
 	"log"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"go.etcd.io/bbolt"
@@ -16,7 +18,7 @@ import (
 
 const bucketName = "CacheBucket"
 
-func QueryWO(database *mongo.Database, boltDB *bbolt.DB, noMax bool, statics, dynamics []string, exercises [9][]string) (map[string]datatypes.DynamicStr, map[string]datatypes.StaticStr, map[string]datatypes.Exercise, datatypes.TransitionMatrix, error) {
+func QueryWO(database *mongo.Database, boltDB *bbolt.DB, tenant string, noMax bool, statics, dynamics []string, exercises [9][]string) (map[string]datatypes.DynamicStr, map[string]datatypes.StaticStr, map[string]datatypes.Exercise, datatypes.TransitionMatrix, error) {
 	var wg sync.WaitGroup
 
 	errChan := make(chan error, 4)
@@ -27,7 +29,7 @@ func QueryWO(database *mongo.Database, boltDB *bbolt.DB, noMax bool, statics, dy
 	go func() {
 		defer wg.Done()
 		var err error
-		dynamicStr, err = GetDynamics(database, boltDB, dynamics)
+		dynamicStr, err = GetDynamics(database, boltDB, tenant, dynamics)
 		if err != nil {
 			errChan <- err
 		}
@@ -37,7 +39,7 @@ func QueryWO(database *mongo.Database, boltDB *bbolt.DB, noMax bool, statics, dy
 	go func() {
 		defer wg.Done()
 		var err error
-		staticStr, err = GetStatics(database, boltDB, statics)
+		staticStr, err = GetStatics(database, boltDB, tenant, statics)
 		if err != nil {
 			errChan <- err
 		}
@@ -47,7 +49,7 @@ func QueryWO(database *mongo.Database, boltDB *bbolt.DB, noMax bool, statics, dy
 	go func() {
 		defer wg.Done()
 		var err error
-		exerciseMap, err = GetExercises(database, boltDB, exercises)
+		exerciseMap, err = GetExercises(database, boltDB, tenant, exercises)
 		if err != nil {
 			errChan <- err
 		}
@@ -57,7 +59,7 @@ func QueryWO(database *mongo.Database, boltDB *bbolt.DB, noMax bool, statics, dy
 	go func() {
 		defer wg.Done()
 		var err error
-		matrix, err = GetTransitionMatrix(database, boltDB)
+		matrix, err = GetTransitionMatrix(database, boltDB, tenant)
 		if err != nil {
 			errChan <- err
 		}
@@ -88,7 +90,7 @@ func QueryWO(database *mongo.Database, boltDB *bbolt.DB, noMax bool, statics, dy
 	return dynamicStr, staticStr, exerciseMap, matrix, nil
 }
 
-func GetExercises(database *mongo.Database, boltDB *bbolt.DB, exercises [9][]string) (map[string]datatypes.Exercise, error) {
+func GetExercises(database *mongo.Database, boltDB *bbolt.DB, tenant string, exercises [9][]string) (map[string]datatypes.Exercise, error) {
 	exerciseMap := map[string]datatypes.Exercise{}
 
 	sumIdList := []string{}
@@ -106,22 +108,20 @@ func GetExercises(database *mongo.Database, boltDB *bbolt.DB, exercises [9][]str
 			return err
 		}
 
-		v := b.Get([]byte("Exercise"))
+		v := b.Get(CacheKey(tenant, "Exercise"))
 		if v != nil {
 			err := json.Unmarshal(v, &exerciseList)
 			if err == nil {
+				metrics.CacheHit("Exercise")
 				return nil
 			}
 			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
 		}
 
-		cursor, err := database.Collection("exercise").Find(context.Background(), bson.D{})
-		if err != nil {
-			return err
-		}
-		defer cursor.Close(context.Background())
+		metrics.CacheMiss("Exercise")
 
-		if err = cursor.All(context.Background(), &exerciseList); err != nil {
+		exerciseList, err = findAll[datatypes.Exercise](database, tenant, ExerciseCollection)
+		if err != nil {
 			return err
 		}
 
@@ -130,11 +130,13 @@ func GetExercises(database *mongo.Database, boltDB *bbolt.DB, exercises [9][]str
 			return err
 		}
 
-		err = b.Put([]byte("Exercise"), data)
+		err = b.Put(CacheKey(tenant, "Exercise"), data)
 		if err != nil {
 			return err
 		}
 
+		metrics.CacheRefill("Exercise")
+
 		return nil
 	})
 
@@ -151,7 +153,7 @@ func GetExercises(database *mongo.Database, boltDB *bbolt.DB, exercises [9][]str
 	return exerciseMap, nil
 }
 
-func GetTransitionMatrix(database *mongo.Database, boltDB *bbolt.DB) (datatypes.TransitionMatrix, error) {
+func GetTransitionMatrix(database *mongo.Database, boltDB *bbolt.DB, tenant string) (datatypes.TransitionMatrix, error) {
 
 	var matrix datatypes.TransitionMatrix
 
@@ -161,16 +163,21 @@ func GetTransitionMatrix(database *mongo.Database, boltDB *bbolt.DB) (datatypes.
 			return err
 		}
 
-		v := b.Get([]byte("Transition"))
+		v := b.Get(CacheKey(tenant, "Transition"))
 		if v != nil {
 			err := json.Unmarshal(v, &matrix)
 			if err == nil {
+				metrics.CacheHit("Transition")
 				return nil
 			}
 			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
 		}
 
-		err = database.Collection("transition").FindOne(context.Background(), bson.D{}).Decode(&matrix)
+		metrics.CacheMiss("Transition")
+
+		start := time.Now()
+		err = Collection(database, tenant, TransitionCollection).FindOne(context.Background(), bson.D{}).Decode(&matrix)
+		metrics.MongoFetch(TransitionCollection, start)
 		if err != nil {
 			return err
 		}
@@ -180,11 +187,13 @@ func GetTransitionMatrix(database *mongo.Database, boltDB *bbolt.DB) (datatypes.
 			return err
 		}
 
-		err = b.Put([]byte("Transition"), data)
+		err = b.Put(CacheKey(tenant, "Transition"), data)
 		if err != nil {
 			return err
 		}
 
+		metrics.CacheRefill("Transition")
+
 		return nil
 	})
 
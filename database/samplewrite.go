@@ -0,0 +1,269 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"i9-pos/datatypes"
+	"log"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrUnknownSampleTarget = errors.New("the sample's exercise or stretch doesn't exist")
+	ErrSampleTargetLinked  = errors.New("the exercise or stretch already links to another sample")
+	ErrSampleIDMismatch    = errors.New("the SampleID doesn't name a sample of this exercise or stretch")
+	ErrSampleStillLinked   = errors.New("a sample still links to this exercise or stretch")
+)
+
+// sampleTarget maps a sample Type to the collection and cache key of the
+// documents it links to.
+func sampleTarget(sampleType string) (string, string, bool) {
+	switch sampleType {
+	case "Exercise":
+		return ExerciseCollection, "Exercise", true
+	case "Dynamic Stretch":
+		return DynamicCollection, "Dynamic", true
+	case "Static Stretch":
+		return StaticCollection, "Static", true
+	default:
+		return "", "", false
+	}
+}
+
+func AllSamples(database *mongo.Database, tenant string) ([]datatypes.Sample, error) {
+	return findAll[datatypes.Sample](database, tenant, SampleCollection)
+}
+
+func SampleByHex(database *mongo.Database, tenant, id string) (datatypes.Sample, error) {
+	var sample datatypes.Sample
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return sample, mongo.ErrNoDocuments
+	}
+
+	err = Collection(database, tenant, SampleCollection).FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&sample)
+
+	return sample, err
+}
+
+// InsertLinkedSample stores a sample and points its exercise or stretch back
+// at it. The target must exist and not already link to another sample. If
+// the back link can't be written the sample is removed again.
+func InsertLinkedSample(database *mongo.Database, boltDB *bbolt.DB, tenant string, sample datatypes.Sample) (datatypes.Sample, error) {
+	if err := checkSampleTarget(database, tenant, sample, ""); err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	sample.ID = primitive.NewObjectID()
+
+	samples := Collection(database, tenant, SampleCollection)
+
+	if _, err := samples.InsertOne(context.Background(), sample); err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	if err := setSampleLink(database, boltDB, tenant, sample.Type, sample.ExOrStID, sample.ID.Hex()); err != nil {
+		undo("remove sample "+sample.ID.Hex(), func() error {
+			_, err := samples.DeleteOne(context.Background(), bson.M{"_id": sample.ID})
+			return err
+		})
+		return datatypes.Sample{}, err
+	}
+
+	if err := catalogChanged(database, boltDB, tenant, "Sample"); err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	return sample, nil
+}
+
+// ReplaceLinkedSample updates a sample, moving the back link when the sample
+// now belongs to a different exercise or stretch. If the links can't be
+// moved the stored sample and its old link are put back.
+func ReplaceLinkedSample(database *mongo.Database, boltDB *bbolt.DB, tenant string, sample datatypes.Sample) (datatypes.Sample, error) {
+	existing, err := SampleByHex(database, tenant, sample.ID.Hex())
+	if err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	if err := checkSampleTarget(database, tenant, sample, sample.ID.Hex()); err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	samples := Collection(database, tenant, SampleCollection)
+
+	if _, err := samples.ReplaceOne(context.Background(), bson.M{"_id": sample.ID}, sample); err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	restore := func() {
+		undo("restore sample "+existing.ID.Hex(), func() error {
+			_, err := samples.ReplaceOne(context.Background(), bson.M{"_id": existing.ID}, existing)
+			return err
+		})
+	}
+
+	moved := existing.Type != sample.Type || existing.ExOrStID != sample.ExOrStID
+
+	if moved {
+		if err := clearSampleLink(database, boltDB, tenant, existing); err != nil {
+			restore()
+			return datatypes.Sample{}, err
+		}
+	}
+
+	if err := setSampleLink(database, boltDB, tenant, sample.Type, sample.ExOrStID, sample.ID.Hex()); err != nil {
+		restore()
+		if moved {
+			undo("relink "+existing.ExOrStID, func() error {
+				return setSampleLink(database, boltDB, tenant, existing.Type, existing.ExOrStID, existing.ID.Hex())
+			})
+		}
+		return datatypes.Sample{}, err
+	}
+
+	if err := catalogChanged(database, boltDB, tenant, "Sample"); err != nil {
+		return datatypes.Sample{}, err
+	}
+
+	return sample, nil
+}
+
+// DeleteLinkedSample removes a sample and clears the SampleID of the exercise
+// or stretch that pointed at it. If the link can't be cleared the sample is
+// put back.
+func DeleteLinkedSample(database *mongo.Database, boltDB *bbolt.DB, tenant, id string) error {
+	existing, err := SampleByHex(database, tenant, id)
+	if err != nil {
+		return err
+	}
+
+	samples := Collection(database, tenant, SampleCollection)
+
+	if _, err := samples.DeleteOne(context.Background(), bson.M{"_id": existing.ID}); err != nil {
+		return err
+	}
+
+	if err := clearSampleLink(database, boltDB, tenant, existing); err != nil {
+		undo("restore sample "+existing.ID.Hex(), func() error {
+			_, err := samples.InsertOne(context.Background(), existing)
+			return err
+		})
+		return err
+	}
+
+	return catalogChanged(database, boltDB, tenant, "Sample")
+}
+
+// undo runs a compensating write after a failed sample write. There is no
+// transaction to roll back, so a failed undo is logged for the link report
+// to pick up.
+func undo(what string, write func() error) {
+	if err := write(); err != nil {
+		log.Printf("Could not %s after a failed sample write: %v", what, err)
+	}
+}
+
+// checkSampleID keeps an exercise or stretch's own SampleID in step with the
+// samples. It may only name a sample that links back to it, and can't be
+// emptied while such a sample exists.
+func checkSampleID(database *mongo.Database, tenant, sampleType, backendID, sampleID string) error {
+	if sampleID == "" {
+		return checkNoSample(database, tenant, sampleType, backendID)
+	}
+
+	sample, err := SampleByHex(database, tenant, sampleID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrSampleIDMismatch
+	} else if err != nil {
+		return err
+	}
+
+	if sample.Type != sampleType || sample.ExOrStID != backendID {
+		return ErrSampleIDMismatch
+	}
+
+	return nil
+}
+
+// checkNoSample refuses when a sample still links to the exercise or stretch.
+func checkNoSample(database *mongo.Database, tenant, sampleType, backendID string) error {
+	count, err := Collection(database, tenant, SampleCollection).CountDocuments(context.Background(), bson.M{"type": sampleType, "exorstid": backendID}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSampleStillLinked
+	}
+
+	return nil
+}
+
+// checkSampleTarget makes sure the sample's exercise or stretch exists and
+// links to no sample, to sampleID itself, or to a sample that no longer
+// exists.
+func checkSampleTarget(database *mongo.Database, tenant string, sample datatypes.Sample, sampleID string) error {
+	collection, _, ok := sampleTarget(sample.Type)
+	if !ok {
+		return ErrUnknownSampleTarget
+	}
+
+	var target struct {
+		SampleID string `bson:"sampleid"`
+	}
+
+	err := Collection(database, tenant, collection).FindOne(context.Background(), bson.M{"backendID": sample.ExOrStID}).Decode(&target)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrUnknownSampleTarget
+	} else if err != nil {
+		return err
+	}
+
+	if target.SampleID == "" || target.SampleID == sampleID {
+		return nil
+	}
+
+	if _, err := SampleByHex(database, tenant, target.SampleID); errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return ErrSampleTargetLinked
+}
+
+func setSampleLink(database *mongo.Database, boltDB *bbolt.DB, tenant, sampleType, backendID, sampleID string) error {
+	collection, cacheKey, ok := sampleTarget(sampleType)
+	if !ok {
+		return ErrUnknownSampleTarget
+	}
+
+	_, err := Collection(database, tenant, collection).UpdateOne(context.Background(), bson.M{"backendID": backendID}, bson.M{"$set": bson.M{"sampleid": sampleID}})
+	if err != nil {
+		return err
+	}
+
+	return ClearCacheKey(boltDB, tenant, cacheKey)
+}
+
+// clearSampleLink empties the old target's SampleID, but only if it still
+// points at this sample.
+func clearSampleLink(database *mongo.Database, boltDB *bbolt.DB, tenant string, sample datatypes.Sample) error {
+	collection, cacheKey, ok := sampleTarget(sample.Type)
+	if !ok {
+		return nil
+	}
+
+	_, err := Collection(database, tenant, collection).UpdateOne(context.Background(), bson.M{"backendID": sample.ExOrStID, "sampleid": sample.ID.Hex()}, bson.M{"$set": bson.M{"sampleid": ""}})
+	if err != nil {
+		return err
+	}
+
+	return ClearCacheKey(boltDB, tenant, cacheKey)
+}
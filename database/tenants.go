@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"i9-pos/datatypes"
+	"i9-pos/metrics"
+	"log"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CacheKey namespaces a CacheBucket key by tenant. The default tenant keeps
+// the bare key so existing caches stay valid.
+func CacheKey(tenant, key string) []byte {
+	if tenant == DefaultTenant {
+		return []byte(key)
+	}
+	return []byte(tenant + "/" + key)
+}
+
+// DefaultTenantSettings are used for a tenant with no settings document.
+func DefaultTenantSettings(tenant string) datatypes.TenantSettings {
+	return datatypes.TenantSettings{
+		Tenant:           tenant,
+		RestPosition:     "resting-position",
+		CongratsPosition: "standing-thumbs-up-wink",
+		StandingPosition: "standing-arms-bent",
+	}
+}
+
+// GetTenantSettings reads a tenant's default positions and catalog version.
+// All tenants share the tenant collection, keyed by tenant ID. Positions the
+// document leaves empty fall back to the defaults.
+func GetTenantSettings(database *mongo.Database, boltDB *bbolt.DB, tenant string) (datatypes.TenantSettings, error) {
+
+	settings := DefaultTenantSettings(tenant)
+
+	err := boltDB.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		if err != nil {
+			return err
+		}
+
+		v := b.Get(CacheKey(tenant, "Tenant"))
+		if v != nil {
+			err := json.Unmarshal(v, &settings)
+			if err == nil {
+				metrics.CacheHit("Tenant")
+				return nil
+			}
+			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
+		}
+
+		metrics.CacheMiss("Tenant")
+
+		var stored datatypes.TenantSettings
+		start := time.Now()
+		err = Collection(database, DefaultTenant, TenantCollection).FindOne(context.Background(), bson.M{"tenant": tenant}).Decode(&stored)
+		metrics.MongoFetch(TenantCollection, start)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+
+		settings = withDefaultPositions(stored, settings)
+
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+
+		if err := b.Put(CacheKey(tenant, "Tenant"), data); err != nil {
+			return err
+		}
+
+		metrics.CacheRefill("Tenant")
+
+		return nil
+	})
+
+	if err != nil {
+		return datatypes.TenantSettings{}, err
+	}
+
+	return settings, nil
+}
+
+func withDefaultPositions(stored, defaults datatypes.TenantSettings) datatypes.TenantSettings {
+	if stored.RestPosition == "" {
+		stored.RestPosition = defaults.RestPosition
+	}
+	if stored.CongratsPosition == "" {
+		stored.CongratsPosition = defaults.CongratsPosition
+	}
+	if stored.StandingPosition == "" {
+		stored.StandingPosition = defaults.StandingPosition
+	}
+	stored.Tenant = defaults.Tenant
+	return stored
+}
+
+// SetDefaultTheme sets the theme workouts use when they don't ask for one.
+// The theme must exist, an empty id clears it.
+func SetDefaultTheme(database *mongo.Database, boltDB *bbolt.DB, tenant, id string) (datatypes.TenantSettings, error) {
+	if id != "" {
+		if _, err := ThemeByBackendID(database, tenant, id); errors.Is(err, mongo.ErrNoDocuments) {
+			return datatypes.TenantSettings{}, ErrUnknownTheme
+		} else if err != nil {
+			return datatypes.TenantSettings{}, err
+		}
+	}
+
+	_, err := Collection(database, DefaultTenant, TenantCollection).UpdateOne(context.Background(),
+		bson.M{"tenant": tenant},
+		bson.M{"$set": bson.M{"defaulttheme": id}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return datatypes.TenantSettings{}, err
+	}
+
+	if err := ClearCacheKey(boltDB, tenant, "Tenant"); err != nil {
+		return datatypes.TenantSettings{}, err
+	}
+
+	return GetTenantSettings(database, boltDB, tenant)
+}
+
+// BumpCatalogVersion records that a tenant's catalog changed, so clients
+// holding the old version know to refetch samples and images.
+func BumpCatalogVersion(database *mongo.Database, boltDB *bbolt.DB, tenant string) error {
+	if err := incCatalogVersion(database, tenant); err != nil {
+		return err
+	}
+
+	return ClearCacheKey(boltDB, tenant, "Tenant")
+}
+
+func incCatalogVersion(database *mongo.Database, tenant string) error {
+	_, err := Collection(database, DefaultTenant, TenantCollection).UpdateOne(context.Background(),
+		bson.M{"tenant": tenant},
+		bson.M{"$inc": bson.M{"catalogversion": 1}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// catalogChanged drops the cached collection and bumps the catalog version
+// after a write.
+func catalogChanged(database *mongo.Database, boltDB *bbolt.DB, tenant, key string) error {
+	if err := ClearCacheKey(boltDB, tenant, key); err != nil {
+		return err
+	}
+	return BumpCatalogVersion(database, boltDB, tenant)
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"i9-pos/datatypes"
+	"i9-pos/metrics"
+	"log"
+	"os"
+	"strings"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrUnknownTheme = errors.New("no theme matches provided id")
+	ErrDefaultTheme = errors.New("the theme is the tenant's default theme")
+)
+
+func AllThemes(database *mongo.Database, tenant string) ([]datatypes.Theme, error) {
+	return findAll[datatypes.Theme](database, tenant, ThemeCollection)
+}
+
+func ThemeByBackendID(database *mongo.Database, tenant, id string) (datatypes.Theme, error) {
+	return findByBackendID[datatypes.Theme](database, tenant, ThemeCollection, id)
+}
+
+func InsertTheme(database *mongo.Database, boltDB *bbolt.DB, tenant string, theme datatypes.Theme) (datatypes.Theme, error) {
+	theme.ID = primitive.NilObjectID
+
+	id, err := insertDoc(database, boltDB, tenant, ThemeCollection, "Theme", theme)
+	if err != nil {
+		return datatypes.Theme{}, err
+	}
+
+	theme.ID = id
+	return theme, nil
+}
+
+func ReplaceTheme(database *mongo.Database, boltDB *bbolt.DB, tenant string, theme datatypes.Theme) (datatypes.Theme, error) {
+	existing, err := ThemeByBackendID(database, tenant, theme.BackendID)
+	if err != nil {
+		return datatypes.Theme{}, err
+	}
+
+	theme.ID = existing.ID
+
+	if err := replaceByBackendID(database, boltDB, tenant, ThemeCollection, "Theme", theme.BackendID, theme); err != nil {
+		return datatypes.Theme{}, err
+	}
+
+	return theme, nil
+}
+
+// DeleteTheme refuses the tenant's default theme, which has to be changed
+// first.
+func DeleteTheme(database *mongo.Database, boltDB *bbolt.DB, tenant, id string) error {
+	settings, err := GetTenantSettings(database, boltDB, tenant)
+	if err != nil {
+		return err
+	}
+	if settings.DefaultTheme == id {
+		return ErrDefaultTheme
+	}
+
+	return deleteByBackendID(database, boltDB, tenant, ThemeCollection, "Theme", id)
+}
+
+// GetThemes reads the tenant's themes through the cache.
+func GetThemes(database *mongo.Database, boltDB *bbolt.DB, tenant string) ([]datatypes.Theme, error) {
+
+	var themeList []datatypes.Theme
+
+	err := boltDB.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		if err != nil {
+			return err
+		}
+
+		v := b.Get(CacheKey(tenant, "Theme"))
+		if v != nil {
+			err := json.Unmarshal(v, &themeList)
+			if err == nil {
+				metrics.CacheHit("Theme")
+				return nil
+			}
+			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
+		}
+
+		metrics.CacheMiss("Theme")
+
+		themeList, err = findAll[datatypes.Theme](database, tenant, ThemeCollection)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(themeList)
+		if err != nil {
+			return err
+		}
+
+		if err := b.Put(CacheKey(tenant, "Theme"), data); err != nil {
+			return err
+		}
+
+		metrics.CacheRefill("Theme")
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return themeList, nil
+}
+
+// ResolveTheme picks the positions for a workout. An empty id falls back to
+// the tenant's default theme, and without one, or if it's gone, to the
+// positions in the tenant settings. Only a requested id can be unknown.
+func ResolveTheme(database *mongo.Database, boltDB *bbolt.DB, tenant, id string, settings datatypes.TenantSettings) (datatypes.Theme, error) {
+	fallback := datatypes.Theme{
+		RestPosition:     settings.RestPosition,
+		CongratsPosition: settings.CongratsPosition,
+		StandingPosition: settings.StandingPosition,
+	}
+
+	requested := id != ""
+	if !requested {
+		id = settings.DefaultTheme
+	}
+
+	if id == "" {
+		return fallback, nil
+	}
+
+	themes, err := GetThemes(database, boltDB, tenant)
+	if err != nil {
+		return datatypes.Theme{}, err
+	}
+
+	for _, theme := range themes {
+		if theme.BackendID == id {
+			return theme, nil
+		}
+	}
+
+	if !requested {
+		log.Printf("Default theme %q of tenant %q doesn't exist, using the settings positions", id, tenant)
+		return fallback, nil
+	}
+
+	return datatypes.Theme{}, ErrUnknownTheme
+}
+
+// ThemeImageSets lists the image sets themes may use that the catalog itself
+// doesn't show: the built in and tenant default positions, and the comma
+// separated THEME_IMAGE_SETS for character poses.
+func ThemeImageSets(settings datatypes.TenantSettings) []string {
+	defaults := DefaultTenantSettings(settings.Tenant)
+
+	imageSets := []string{
+		defaults.RestPosition, defaults.CongratsPosition, defaults.StandingPosition,
+		settings.RestPosition, settings.CongratsPosition, settings.StandingPosition,
+	}
+
+	for _, id := range strings.Split(os.Getenv("THEME_IMAGE_SETS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			imageSets = append(imageSets, id)
+		}
+	}
+
+	return UniqueStrSlice(imageSets)
+}
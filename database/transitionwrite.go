@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"i9-pos/datatypes"
+	"log"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrUnknownSpeed = errors.New("speed must be fast, regular or slow")
+
+// matrixField maps a transition speed to its bson field and grid.
+func matrixField(matrix *datatypes.TransitionMatrix, speed string) (string, *[11][11]datatypes.TransitionRep, error) {
+	switch speed {
+	case "fast":
+		return "fastmatrix", &matrix.FastMatrix, nil
+	case "regular":
+		return "regularmatrix", &matrix.RegularMatrix, nil
+	case "slow":
+		return "slowmatrix", &matrix.SlowMatrix, nil
+	default:
+		return "", nil, ErrUnknownSpeed
+	}
+}
+
+// TransitionCell reads one cell straight from MongoDB.
+func TransitionCell(database *mongo.Database, tenant, speed string, from, to int) (datatypes.TransitionRep, error) {
+	var matrix datatypes.TransitionMatrix
+
+	if err := Collection(database, tenant, TransitionCollection).FindOne(context.Background(), bson.D{}).Decode(&matrix); err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	_, grid, err := matrixField(&matrix, speed)
+	if err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	return grid[from][to], nil
+}
+
+// UpdateTransitionCell writes one cell, records the change in the
+// transitionhistory collection and refills the cached matrix.
+func UpdateTransitionCell(database *mongo.Database, boltDB *bbolt.DB, tenant, speed, from, to string, rep datatypes.TransitionRep, changedBy string) (datatypes.TransitionRep, error) {
+	fromIndex, fromOK := datatypes.ParentMatIndex[from]
+	toIndex, toOK := datatypes.ParentMatIndex[to]
+	if !fromOK || !toOK {
+		return datatypes.TransitionRep{}, fmt.Errorf("unknown parent %q or %q", from, to)
+	}
+
+	var matrix datatypes.TransitionMatrix
+
+	if err := Collection(database, tenant, TransitionCollection).FindOne(context.Background(), bson.D{}).Decode(&matrix); err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	field, grid, err := matrixField(&matrix, speed)
+	if err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	before := grid[fromIndex][toIndex]
+
+	change := datatypes.TransitionChange{
+		Speed:     speed,
+		From:      from,
+		To:        to,
+		Before:    before,
+		After:     rep,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now().UTC(),
+	}
+
+	// The history record goes in first, unapplied, so a cell never changes
+	// without one. It is marked applied once the cell update has landed.
+	history := Collection(database, tenant, TransitionHistoryCollection)
+
+	inserted, err := history.InsertOne(context.Background(), change)
+	if err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	cellField := fmt.Sprintf("%s.%d.%d", field, fromIndex, toIndex)
+	_, err = Collection(database, tenant, TransitionCollection).UpdateOne(context.Background(), bson.M{"_id": matrix.ID}, bson.M{"$set": bson.M{cellField: rep}})
+	if err != nil {
+		if _, deleteErr := history.DeleteOne(context.Background(), bson.M{"_id": inserted.InsertedID}); deleteErr != nil {
+			log.Printf("Could not remove unapplied transition change %v: %v", inserted.InsertedID, deleteErr)
+		}
+		return datatypes.TransitionRep{}, err
+	}
+
+	if _, err := history.UpdateOne(context.Background(), bson.M{"_id": inserted.InsertedID}, bson.M{"$set": bson.M{"applied": true}}); err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	if err := catalogChanged(database, boltDB, tenant, "Transition"); err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	if _, err := GetTransitionMatrix(database, boltDB, tenant); err != nil {
+		return datatypes.TransitionRep{}, err
+	}
+
+	return rep, nil
+}
+
+// TransitionHistory returns the most recent applied matrix changes, newest
+// first.
+func TransitionHistory(database *mongo.Database, tenant string, limit int64) ([]datatypes.TransitionChange, error) {
+	history := []datatypes.TransitionChange{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "changedat", Value: -1}}).SetLimit(limit)
+
+	cursor, err := Collection(database, tenant, TransitionHistoryCollection).Find(context.Background(), bson.M{"applied": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	if err = cursor.All(context.Background(), &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -1,6 +1,11 @@
 package datatypes
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // Exists in DB as actual entry
 // Deprecated
@@ -122,6 +127,7 @@ type StretchWorkout struct {
 	CongratsPosition string             `bson:"congratspos"`
 	StandingPosition string             `bson:"standingpos"`
 	RoundTime        float32            `bson:"roundtime"`
+	CatalogVersion   int64              `bson:"catalogversion"`
 }
 
 // Programatically created as actual entry in DB
@@ -140,6 +146,23 @@ type Workout struct {
 	CongratsPosition string             `bson:"congratspos"`
 	StandingPosition string             `bson:"standingpos"`
 	Exercises        [9]WORound         `bson:"exercises"`
+	CatalogVersion   int64              `bson:"catalogversion"`
+}
+
+// Exercise parent families, indexing the rows and columns of the transition
+// matrices
+var ParentMatIndex = map[string]int{
+	"Pushups":           0,
+	"Squats":            1,
+	"Burpees":           2,
+	"Jumps":             3,
+	"Lunges":            4,
+	"Mountain Climbers": 5,
+	"Abs":               6,
+	"Bridges":           7,
+	"Kicks":             8,
+	"Planks":            9,
+	"Supermans":         10,
 }
 
 type TransitionRep struct {
@@ -154,3 +177,100 @@ type TransitionMatrix struct {
 	RegularMatrix [11][11]TransitionRep `bson:"regularmatrix"`
 	SlowMatrix    [11][11]TransitionRep `bson:"slowmatrix"`
 }
+
+// Programatically created as actual entry in DB
+type TransitionChange struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Speed     string             `bson:"speed"`
+	From      string             `bson:"from"`
+	To        string             `bson:"to"`
+	Before    TransitionRep      `bson:"before"`
+	After     TransitionRep      `bson:"after"`
+	ChangedBy string             `bson:"changedby"`
+	ChangedAt time.Time          `bson:"changedat"`
+	Applied   bool               `bson:"applied"`
+}
+
+// Programatically created from the catalog, never stored
+type SampleLinkProblem struct {
+	SampleID string
+	Type     string
+	ExOrStID string
+	Problem  string
+}
+
+// Programatically created from the catalog, never stored
+type SampleLinkReport struct {
+	OrphanedSamples []SampleLinkProblem
+	BrokenSampleIDs []SampleLinkProblem
+}
+
+// Programatically created from the DB or an export file, never stored
+type Catalog struct {
+	Exercises   []Exercise
+	Dynamics    []DynamicStr
+	Statics     []StaticStr
+	Samples     []Sample
+	Transitions []TransitionMatrix
+	Themes      []Theme
+}
+
+// Programatically created from the catalog, never stored
+type LintProblem struct {
+	Severity   string
+	Collection string
+	BackendID  string
+	Problem    string
+}
+
+// Programatically created by the catalog export, stored in the archive
+type CatalogManifest struct {
+	Version    int
+	ExportedAt time.Time
+	Database   string
+	Tenant     string
+	Counts     map[string]int
+}
+
+// Programatically created by the catalog export, never stored in the DB.
+// Documents stay raw so fields the structs don't know about survive a round
+// trip.
+type CatalogArchive struct {
+	Manifest    CatalogManifest
+	Collections map[string][]bson.D
+}
+
+// Programatically created by a catalog import, never stored
+type CatalogChange struct {
+	Collection string
+	ID         string
+	Action     string
+	Fields     []string
+}
+
+// Programatically created by a catalog import, never stored
+type ImportReport struct {
+	Changes   []CatalogChange
+	Unchanged map[string]int
+}
+
+// Exists in DB as actual entry, one per tenant
+type TenantSettings struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	Tenant           string             `bson:"tenant"`
+	RestPosition     string             `bson:"restposition"`
+	CongratsPosition string             `bson:"congratsposition"`
+	StandingPosition string             `bson:"standingposition"`
+	DefaultTheme     string             `bson:"defaulttheme"`
+	CatalogVersion   int64              `bson:"catalogversion"`
+}
+
+// Exists in DB as actual entry
+type Theme struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	BackendID        string             `bson:"backendID"`
+	Name             string             `bson:"name"`
+	RestPosition     string             `bson:"restposition"`
+	CongratsPosition string             `bson:"congratsposition"`
+	StandingPosition string             `bson:"standingposition"`
+}
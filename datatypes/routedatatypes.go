@@ -7,6 +7,7 @@ type StretchWorkoutRoute struct {
 	Statics      []string
 	StretchTimes StretchTimes
 	ID           primitive.ObjectID
+	ThemeID      string
 }
 
 type StretchTimes struct {
@@ -25,6 +26,7 @@ type WorkoutRoute struct {
 	ID           primitive.ObjectID
 	Difficulty   int
 	Exercises    [9]WorkoutRound
+	ThemeID      string
 }
 
 type ExerciseTimes struct {
@@ -0,0 +1,60 @@
+package datatypes
+
+// Programatically created from a generated workout, never stored
+type TimelineFrame struct {
+	Position string
+	Start    float32
+	Duration float32
+}
+
+// Programatically created from a generated workout, never stored
+type TimelineRep struct {
+	Name        string
+	Count       int
+	Of          int
+	Transition  bool
+	SwitchSides bool
+	Start       float32
+	FullTime    float32
+	Frames      []TimelineFrame
+}
+
+// Programatically created from a generated workout, never stored
+type TimelineBlock struct {
+	Kind     string
+	Name     string
+	Round    int
+	Start    float32
+	FullTime float32
+	Reps     []TimelineRep
+}
+
+// Programatically created from a generated workout, never stored
+type Timeline struct {
+	Blocks   []TimelineBlock
+	FullTime float32
+}
+
+// Programatically created from a timeline, never stored
+type Cue struct {
+	Start float32
+	End   float32
+	Text  string
+}
+
+// Programatically created from a timeline, never stored
+type CoachingCue struct {
+	Start float32
+	Kind  string
+	Text  string
+}
+
+// Programatically created from a timeline, never stored
+type PreloadImage struct {
+	ImageSetID  string
+	FirstNeeded float32
+	Low         string `json:",omitempty"`
+	Mid         string `json:",omitempty"`
+	High        string `json:",omitempty"`
+	Original    string `json:",omitempty"`
+}
@@ -1,40 +1,39 @@
 package gets
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"i9-pos/database"
 	"i9-pos/datatypes"
+	"i9-pos/metrics"
+	"i9-pos/platform/apierror"
+	"i9-pos/platform/middleware"
 	"log"
 	"slices"
 
 	"github.com/gin-gonic/gin"
 	"go.etcd.io/bbolt"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 const bucketName = "CacheBucket"
 
+var ErrUnknownSample = errors.New("no sample matches provided id")
+
 func GetSampleByID(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
+		tenant := middleware.GetTenant(c)
+
 		idStr, exists := c.Params.Get("id")
 		if !exists {
-			c.JSON(400, gin.H{
-				"Error": "Issue with param",
-				"Exact": "Unable to get ID from URL parameter",
-			})
+			apierror.BadRequest(c, "Issue with param", "Unable to get ID from URL parameter")
 			return
 		}
 
-		sample, err := SampleByID(db, boltDB, idStr)
+		sample, err := SampleByID(db, boltDB, tenant, idStr)
 		if err != nil {
-			c.JSON(400, gin.H{
-				"Error": "Issue with querying sample",
-				"Exact": err.Error(),
-			})
+			writeSampleError(c, "Issue with querying sample", err)
 			return
 		}
 
@@ -43,9 +42,9 @@ func GetSampleByID(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	}
 }
 
-func SampleByID(db *mongo.Database, boltDB *bbolt.DB, id string) (datatypes.Sample, error) {
+func SampleByID(db *mongo.Database, boltDB *bbolt.DB, tenant, id string) (datatypes.Sample, error) {
 
-	samples, err := BoltSamples(db, boltDB)
+	samples, err := BoltSamples(db, boltDB, tenant)
 	if err != nil {
 		return datatypes.Sample{}, err
 	}
@@ -56,36 +55,29 @@ func SampleByID(db *mongo.Database, boltDB *bbolt.DB, id string) (datatypes.Samp
 		}
 	}
 
-	return datatypes.Sample{}, errors.New("no matches for sample id")
+	return datatypes.Sample{}, ErrUnknownSample
 }
 
 func GetSampleByExtID(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
+		tenant := middleware.GetTenant(c)
+
 		typeStr, exists := c.Params.Get("type")
 		if !exists || (typeStr != "exercise" && typeStr != "static" && typeStr != "dynamic") {
-			c.JSON(400, gin.H{
-				"Error": "Issue with param",
-				"Exact": "Unable to get ID from URL parameter",
-			})
+			apierror.BadRequest(c, "Issue with param", "type must be one of exercise, static or dynamic")
 			return
 		}
 
 		idStr, exists := c.Params.Get("id")
 		if !exists {
-			c.JSON(400, gin.H{
-				"Error": "Issue with param",
-				"Exact": "Unable to get ID from URL parameter",
-			})
+			apierror.BadRequest(c, "Issue with param", "Unable to get ID from URL parameter")
 			return
 		}
 
-		sample, err := SampleByExtID(db, boltDB, idStr, typeStr)
+		sample, err := SampleByExtID(db, boltDB, tenant, idStr, typeStr)
 		if err != nil {
-			c.JSON(400, gin.H{
-				"Error": "Issue with querying sample",
-				"Exact": err.Error(),
-			})
+			writeSampleError(c, "Issue with querying sample", err)
 			return
 		}
 
@@ -94,7 +86,7 @@ func GetSampleByExtID(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	}
 }
 
-func SampleByExtID(db *mongo.Database, boltDB *bbolt.DB, id, typeStr string) (datatypes.Sample, error) {
+func SampleByExtID(db *mongo.Database, boltDB *bbolt.DB, tenant, id, typeStr string) (datatypes.Sample, error) {
 
 	var formattedType string
 	if typeStr == "exercise" {
@@ -105,7 +97,7 @@ func SampleByExtID(db *mongo.Database, boltDB *bbolt.DB, id, typeStr string) (da
 		formattedType = "Dynamic Stretch"
 	}
 
-	samples, err := BoltSamples(db, boltDB)
+	samples, err := BoltSamples(db, boltDB, tenant)
 	if err != nil {
 		return datatypes.Sample{}, err
 	}
@@ -116,30 +108,37 @@ func SampleByExtID(db *mongo.Database, boltDB *bbolt.DB, id, typeStr string) (da
 		}
 	}
 
-	return datatypes.Sample{}, errors.New("no sample matches provided id")
+	return datatypes.Sample{}, ErrUnknownSample
+}
+
+// writeSampleError answers 404 for a sample the catalog doesn't hold and 503
+// when the samples can't be loaded.
+func writeSampleError(c *gin.Context, message string, err error) {
+	if errors.Is(err, ErrUnknownSample) {
+		metrics.UnknownIDs("sample", 1)
+		apierror.NotFound(c, message)
+		return
+	}
+	apierror.Unavailable(c, message, err)
 }
 
 func GetSamples(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
+		tenant := middleware.GetTenant(c)
+
 		if idList, ok := c.GetQueryArray("idList"); ok {
-			samples, err := GetSamplesByList(db, boltDB, idList)
+			samples, err := GetSamplesByList(db, boltDB, tenant, idList)
 			if err != nil {
-				c.JSON(400, gin.H{
-					"Error": "Issue with querying samples",
-					"Exact": err.Error(),
-				})
+				apierror.Unavailable(c, "Issue with querying samples", err)
 				return
 			}
 
 			c.JSON(200, samples)
 		} else {
-			samples, err := BoltSamples(db, boltDB)
+			samples, err := BoltSamples(db, boltDB, tenant)
 			if err != nil {
-				c.JSON(400, gin.H{
-					"Error": "Issue with querying samples",
-					"Exact": err.Error(),
-				})
+				apierror.Unavailable(c, "Issue with querying samples", err)
 				return
 			}
 
@@ -149,12 +148,12 @@ func GetSamples(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	}
 }
 
-func GetSamplesByList(db *mongo.Database, boltDB *bbolt.DB, idList []string) (map[string]datatypes.Sample, error) {
+func GetSamplesByList(db *mongo.Database, boltDB *bbolt.DB, tenant string, idList []string) (map[string]datatypes.Sample, error) {
 	samples := map[string]datatypes.Sample{}
 
 	uniqueSampleIDs := database.UniqueStrSlice(idList)
 
-	sampleSlice, err := BoltSamples(db, boltDB)
+	sampleSlice, err := BoltSamples(db, boltDB, tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -169,7 +168,7 @@ func GetSamplesByList(db *mongo.Database, boltDB *bbolt.DB, idList []string) (ma
 
 }
 
-func BoltSamples(database *mongo.Database, boltDB *bbolt.DB) ([]datatypes.Sample, error) {
+func BoltSamples(db *mongo.Database, boltDB *bbolt.DB, tenant string) ([]datatypes.Sample, error) {
 	var samples []datatypes.Sample
 
 	err := boltDB.Update(func(tx *bbolt.Tx) error {
@@ -178,22 +177,20 @@ func BoltSamples(database *mongo.Database, boltDB *bbolt.DB) ([]datatypes.Sample
 			return err
 		}
 
-		v := b.Get([]byte("Sample"))
+		v := b.Get(database.CacheKey(tenant, "Sample"))
 		if v != nil {
 			err := json.Unmarshal(v, &samples)
 			if err == nil {
+				metrics.CacheHit("Sample")
 				return nil
 			}
 			log.Printf("Failed to unmarshal from bbolt: %v, fetching from MongoDB", err)
 		}
 
-		cursor, err := database.Collection("sample").Find(context.Background(), bson.D{})
-		if err != nil {
-			return err
-		}
-		defer cursor.Close(context.Background())
+		metrics.CacheMiss("Sample")
 
-		if err = cursor.All(context.Background(), &samples); err != nil {
+		samples, err = database.AllSamples(db, tenant)
+		if err != nil {
 			return err
 		}
 
@@ -202,11 +199,13 @@ func BoltSamples(database *mongo.Database, boltDB *bbolt.DB) ([]datatypes.Sample
 			return err
 		}
 
-		err = b.Put([]byte("Sample"), data)
+		err = b.Put(database.CacheKey(tenant, "Sample"), data)
 		if err != nil {
 			return err
 		}
 
+		metrics.CacheRefill("Sample")
+
 		return nil
 	})
 
@@ -216,3 +215,19 @@ func BoltSamples(database *mongo.Database, boltDB *bbolt.DB) ([]datatypes.Sample
 
 	return samples, nil
 }
+
+func GetThemes(db *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		tenant := middleware.GetTenant(c)
+
+		themes, err := database.GetThemes(db, boltDB, tenant)
+		if err != nil {
+			apierror.Unavailable(c, "Issue with querying themes", err)
+			return
+		}
+
+		c.JSON(200, themes)
+
+	}
+}
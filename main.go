@@ -30,20 +30,28 @@ func main() {
 	}
 	defer database.DisConnectDB(client)
 
-	firebaseConfigBase64 := os.Getenv("FIREBASE_CONFIG_BASE64")
-	if firebaseConfigBase64 == "" {
-		log.Fatal("FIREBASE_CONFIG_BASE64 environment variable is not set.")
+	// Only the server builds indexes, so catalog-lint and catalog-archive
+	// still run against a catalog whose duplicates would make this fail.
+	if err := database.EnsureIndexes(db, database.Tenants()); err != nil {
+		log.Fatal(err)
 	}
 
-	configJSON, err := base64.StdEncoding.DecodeString(firebaseConfigBase64)
-	if err != nil {
-		log.Fatalf("Error decoding FIREBASE_CONFIG_BASE64: %v", err)
-	}
+	var firebaseApp *firebase.App
 
-	sa := option.WithCredentialsJSON(configJSON)
-	firebase, err := firebase.NewApp(context.Background(), nil, sa)
-	if err != nil {
-		log.Fatalf("error initializing app: %v\n", err)
+	firebaseConfigBase64 := os.Getenv("FIREBASE_CONFIG_BASE64")
+	if firebaseConfigBase64 == "" {
+		log.Println("FIREBASE_CONFIG_BASE64 is not set, Firebase ID tokens will be verified without credentials.")
+	} else {
+		configJSON, err := base64.StdEncoding.DecodeString(firebaseConfigBase64)
+		if err != nil {
+			log.Fatalf("Error decoding FIREBASE_CONFIG_BASE64: %v", err)
+		}
+
+		sa := option.WithCredentialsJSON(configJSON)
+		firebaseApp, err = firebase.NewApp(context.Background(), nil, sa)
+		if err != nil {
+			log.Fatalf("error initializing app: %v\n", err)
+		}
 	}
 
 	boltDB, err := bbolt.Open("cache.db", 0666, nil)
@@ -52,7 +60,7 @@ func main() {
 	}
 	defer boltDB.Close()
 
-	rtr := platform.New(db, firebase, boltDB)
+	rtr := platform.New(db, firebaseApp, boltDB)
 
 	port := os.Getenv("PORT")
 	if port == "" {
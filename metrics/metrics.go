@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Recorder receives what the server, the catalog cache and the workout
+// generator report. The server installs a Prometheus recorder at startup,
+// anything else, like the catalog commands, records nothing.
+type Recorder interface {
+	Request(method, route string, status int, duration time.Duration)
+	CacheHit(key string)
+	CacheMiss(key string)
+	CacheRefill(key string)
+	MongoFetch(collection string, duration time.Duration)
+	RoundGeneration(status string, duration time.Duration)
+	WorkoutGeneration(kind string, duration time.Duration)
+	ValidationFailure(route string)
+	UnknownIDs(kind string, count int)
+}
+
+var recorder Recorder = nopRecorder{}
+
+// Set installs the recorder. It must be called before the server starts
+// handling requests.
+func Set(r Recorder) {
+	recorder = r
+}
+
+func Request(method, route string, status int, start time.Time) {
+	recorder.Request(method, route, status, time.Since(start))
+}
+
+func CacheHit(key string) {
+	recorder.CacheHit(key)
+}
+
+func CacheMiss(key string) {
+	recorder.CacheMiss(key)
+}
+
+func CacheRefill(key string) {
+	recorder.CacheRefill(key)
+}
+
+// MongoFetch records how long a read from collection took, measured from
+// start, so it can be deferred at the top of a query.
+func MongoFetch(collection string, start time.Time) {
+	recorder.MongoFetch(collection, time.Since(start))
+}
+
+// RoundGeneration records how long building a Regular, Combo or Split round
+// took.
+func RoundGeneration(status string, start time.Time) {
+	recorder.RoundGeneration(status, time.Since(start))
+}
+
+// WorkoutGeneration records how long building a whole "workout" or "stretch"
+// workout took.
+func WorkoutGeneration(kind string, start time.Time) {
+	recorder.WorkoutGeneration(kind, time.Since(start))
+}
+
+func ValidationFailure(route string) {
+	recorder.ValidationFailure(route)
+}
+
+// UnknownIDs counts IDs of a kind, like "exercise" or "sample", that a
+// request named but the catalog doesn't hold.
+func UnknownIDs(kind string, count int) {
+	if count > 0 {
+		recorder.UnknownIDs(kind, count)
+	}
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Request(string, string, int, time.Duration) {}
+func (nopRecorder) CacheHit(string)                            {}
+func (nopRecorder) CacheMiss(string)                           {}
+func (nopRecorder) CacheRefill(string)                         {}
+func (nopRecorder) MongoFetch(string, time.Duration)           {}
+func (nopRecorder) RoundGeneration(string, time.Duration)      {}
+func (nopRecorder) WorkoutGeneration(string, time.Duration)    {}
+func (nopRecorder) ValidationFailure(string)                   {}
+func (nopRecorder) UnknownIDs(string, int)                     {}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "i9pos"
+
+// Prometheus records into its own registry, which Handler serves.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	cacheHits         *prometheus.CounterVec
+	cacheMisses       *prometheus.CounterVec
+	cacheRefills      *prometheus.CounterVec
+	mongoFetch        *prometheus.HistogramVec
+	roundGeneration   *prometheus.HistogramVec
+	workoutGeneration *prometheus.HistogramVec
+	validation        *prometheus.CounterVec
+	unknownIDs        *prometheus.CounterVec
+}
+
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Requests handled, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Request latency, by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		cacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "bbolt cache reads answered from the cache, by key.",
+		}, []string{"key"}),
+		cacheMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "bbolt cache reads that fell through to Mongo, by key.",
+		}, []string{"key"}),
+		cacheRefills: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_refills_total",
+			Help:      "bbolt cache entries written back after a miss, by key.",
+		}, []string{"key"}),
+		mongoFetch: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "mongo_fetch_duration_seconds",
+			Help:      "Mongo read latency, by collection.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"collection"}),
+		roundGeneration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "round_generation_duration_seconds",
+			Help:      "Time to build one workout round, by round status.",
+			Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1},
+		}, []string{"status"}),
+		workoutGeneration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "workout_generation_duration_seconds",
+			Help:      "Time to build a workout including catalog reads, by kind.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"kind"}),
+		validation: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "validation_failures_total",
+			Help:      "Requests refused for validation problems, by route.",
+		}, []string{"route"}),
+		unknownIDs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "unknown_ids_total",
+			Help:      "IDs requested that the catalog doesn't hold, by kind.",
+		}, []string{"kind"}),
+	}
+
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.requests,
+		p.requestDuration,
+		p.cacheHits,
+		p.cacheMisses,
+		p.cacheRefills,
+		p.mongoFetch,
+		p.roundGeneration,
+		p.workoutGeneration,
+		p.validation,
+		p.unknownIDs,
+	)
+
+	return p
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+func (p *Prometheus) Request(method, route string, status int, duration time.Duration) {
+	p.requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	p.requestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) CacheHit(key string) {
+	p.cacheHits.WithLabelValues(key).Inc()
+}
+
+func (p *Prometheus) CacheMiss(key string) {
+	p.cacheMisses.WithLabelValues(key).Inc()
+}
+
+func (p *Prometheus) CacheRefill(key string) {
+	p.cacheRefills.WithLabelValues(key).Inc()
+}
+
+func (p *Prometheus) MongoFetch(collection string, duration time.Duration) {
+	p.mongoFetch.WithLabelValues(collection).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) RoundGeneration(status string, duration time.Duration) {
+	p.roundGeneration.WithLabelValues(status).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) WorkoutGeneration(kind string, duration time.Duration) {
+	p.workoutGeneration.WithLabelValues(kind).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) ValidationFailure(route string) {
+	p.validation.WithLabelValues(route).Inc()
+}
+
+func (p *Prometheus) UnknownIDs(kind string, count int) {
+	p.unknownIDs.WithLabelValues(kind).Add(float64(count))
+}
@@ -0,0 +1,107 @@
+package apierror
+
+import (
+	"i9-pos/metrics"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIDKey = "requestID"
+
+// Codes clients can branch on. The message next to them is meant for people
+// and may change.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeUnresolvableIDs    = "unresolvable_ids"
+	CodeRateLimited        = "rate_limited"
+	CodeInternal           = "internal"
+	CodeCatalogUnavailable = "catalog_unavailable"
+)
+
+// Error is the body every failed request answers with, wrapped in an
+// "error" field.
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// Abort answers the request with the envelope and stops the handler chain.
+func Abort(c *gin.Context, status int, code, message string, details interface{}) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": Error{
+			Code:      code,
+			Message:   message,
+			Details:   details,
+			RequestID: RequestID(c),
+		},
+	})
+}
+
+// BadRequest answers 400 for a malformed body, parameter or query.
+func BadRequest(c *gin.Context, message string, details interface{}) {
+	Abort(c, http.StatusBadRequest, CodeInvalidRequest, message, details)
+}
+
+// Validation answers 400 with the problems found in an otherwise well formed
+// request.
+func Validation(c *gin.Context, problems []string) {
+	metrics.ValidationFailure(c.FullPath())
+	Abort(c, http.StatusBadRequest, CodeValidationFailed, "Issue with validation", problems)
+}
+
+func Unauthorized(c *gin.Context, message string) {
+	Abort(c, http.StatusUnauthorized, CodeUnauthorized, message, nil)
+}
+
+func Forbidden(c *gin.Context, message string) {
+	Abort(c, http.StatusForbidden, CodeForbidden, message, nil)
+}
+
+func NotFound(c *gin.Context, message string) {
+	Abort(c, http.StatusNotFound, CodeNotFound, message, nil)
+}
+
+func Conflict(c *gin.Context, message string, details interface{}) {
+	Abort(c, http.StatusConflict, CodeConflict, message, details)
+}
+
+// Unresolvable answers 422 when the request is well formed but names IDs the
+// catalog doesn't hold.
+func Unresolvable(c *gin.Context, message string, details interface{}) {
+	Abort(c, http.StatusUnprocessableEntity, CodeUnresolvableIDs, message, details)
+}
+
+func RateLimited(c *gin.Context) {
+	Abort(c, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded", nil)
+}
+
+// Unavailable answers 503 when Mongo or the cache in front of it fails. The
+// error is logged against the request ID and never sent to the client.
+func Unavailable(c *gin.Context, message string, err error) {
+	log.Printf("%s [%s]: %v", message, RequestID(c), err)
+	Abort(c, http.StatusServiceUnavailable, CodeCatalogUnavailable, message, nil)
+}
+
+// Internal answers 500, logging the error the same way Unavailable does.
+func Internal(c *gin.Context, message string, err error) {
+	log.Printf("%s [%s]: %v", message, RequestID(c), err)
+	Abort(c, http.StatusInternalServerError, CodeInternal, message, nil)
+}
+
+// RequestID returns the ID the request ID middleware gave the request.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+func SetRequestID(c *gin.Context, id string) {
+	c.Set(requestIDKey, id)
+}
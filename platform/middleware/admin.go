@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"i9-pos/platform/apierror"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware lets a request through only when its Firebase or local
+// token carries the admin role, either as a "role" claim, in a "roles" claim
+// list or as an "admin" claim set to true. The role name defaults to "admin"
+// and can be changed with ADMIN_ROLE.
+func AdminMiddleware(auth *Auth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		principal, ok := authenticate(auth, c)
+		if !ok {
+			return
+		}
+
+		if !hasAdminRole(principal.Claims) {
+			log.Printf("Admin access denied for %q (%s): %s %s", principal.UID, principal.Provider, c.Request.Method, c.Request.URL.Path)
+			apierror.Forbidden(c, "Admin role required")
+			return
+		}
+
+		log.Printf("Admin access by %q (%s): %s %s", principal.UID, principal.Provider, c.Request.Method, c.Request.URL.Path)
+
+		c.Next()
+	}
+}
+
+func hasAdminRole(claims map[string]interface{}) bool {
+	adminRole := os.Getenv("ADMIN_ROLE")
+	if adminRole == "" {
+		adminRole = "admin"
+	}
+
+	if slices.Contains(claimRoles(claims), adminRole) {
+		return true
+	}
+
+	if admin, ok := claims[adminRole].(bool); ok && admin {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/auth"
+)
+
+// Auth holds the token provider the middleware verifies non-local tokens
+// with. Local tokens are always recognised by their issuer.
+type Auth struct {
+	Provider        string
+	FirebaseClient  *auth.Client
+	FirebaseOffline *FirebaseVerifier
+	OIDC            *OIDCProvider
+}
+
+// NewAuth picks the provider named by AUTH_PROVIDER, "firebase" by default or
+// "oidc" for a generic OpenID Connect issuer. The Firebase auth client is made
+// once here; without a Firebase app, tokens are verified offline.
+func NewAuth(app *firebase.App) (*Auth, error) {
+	a := &Auth{
+		Provider: os.Getenv("AUTH_PROVIDER"),
+	}
+
+	switch a.Provider {
+	case "", "firebase":
+		a.Provider = "firebase"
+
+		if app != nil {
+			client, err := app.Auth(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("error getting Auth client: %w", err)
+			}
+			a.FirebaseClient = client
+			break
+		}
+
+		verifier, err := NewFirebaseVerifier()
+		if err != nil {
+			return nil, err
+		}
+		a.FirebaseOffline = verifier
+		log.Printf("Verifying Firebase ID tokens offline for project %s", verifier.ProjectID)
+	case "oidc":
+		provider, err := NewOIDCProvider()
+		if err != nil {
+			return nil, err
+		}
+		a.OIDC = provider
+	default:
+		return nil, fmt.Errorf("unknown AUTH_PROVIDER %q", a.Provider)
+	}
+
+	return a, nil
+}
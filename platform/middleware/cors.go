@@ -1,13 +1,145 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig is the cross origin policy. Origins holds exact origins such as
+// "https://app.example.com" and wildcard subdomain patterns such as
+// "https://*.example.com". A lone "*" allows every origin, in which case
+// credentials are never allowed.
+type CORSConfig struct {
+	Origins          []string
+	Methods          []string
+	Headers          []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+// corsProfiles are the per environment defaults picked by APP_ENV.
+var corsProfiles = map[string]CORSConfig{
+	"development": {
+		Origins:          []string{"http://localhost:*", "http://127.0.0.1:*"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	},
+	"production": {
+		Origins:          []string{},
+		AllowCredentials: true,
+		MaxAge:           86400,
+	},
+}
+
+var defaultCORSMethods = []string{"POST", "OPTIONS", "GET", "PUT", "PATCH", "DELETE"}
+
+var defaultCORSHeaders = []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With", TenantHeader, RequestIDHeader}
+
+var defaultCORSExposedHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", RequestIDHeader}
+
+// LoadCORSConfig starts from the APP_ENV profile, development when unset, and
+// applies the comma separated CORS_ORIGINS, CORS_METHODS, CORS_HEADERS and
+// CORS_EXPOSED_HEADERS lists, CORS_MAX_AGE in seconds and
+// CORS_ALLOW_CREDENTIALS.
+func LoadCORSConfig() CORSConfig {
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "development"
+	}
+
+	config, ok := corsProfiles[env]
+	if !ok {
+		log.Printf("No CORS profile for APP_ENV %q, using production", env)
+		config = corsProfiles["production"]
+	}
+
+	config.Methods = defaultCORSMethods
+	config.Headers = defaultCORSHeaders
+	config.ExposedHeaders = defaultCORSExposedHeaders
+
+	if origins := envList("CORS_ORIGINS"); origins != nil {
+		config.Origins = origins
+	}
+	if methods := envList("CORS_METHODS"); methods != nil {
+		config.Methods = methods
+	}
+	if headers := envList("CORS_HEADERS"); headers != nil {
+		config.Headers = headers
+	}
+	if exposed := envList("CORS_EXPOSED_HEADERS"); exposed != nil {
+		config.ExposedHeaders = exposed
+	}
+	if maxAge, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE")); err == nil {
+		config.MaxAge = maxAge
+	}
+	if credentials, err := strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS")); err == nil {
+		config.AllowCredentials = credentials
+	}
+
+	if len(config.Origins) == 0 {
+		log.Printf("Warning: CORS allows no origins for APP_ENV %q, browser clients are blocked until CORS_ORIGINS is set", env)
+	}
+
+	return config
+}
+
+func envList(name string) []string {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// CORSMiddleware echoes back an allowed request origin, never a wildcard
+// together with credentials, and answers preflight requests.
+func CORSMiddleware(config CORSConfig) gin.HandlerFunc {
+	anyOrigin := slices.Contains(config.Origins, "*")
+	methods := strings.Join(config.Methods, ", ")
+	headers := strings.Join(config.Headers, ", ")
+	exposed := strings.Join(config.ExposedHeaders, ", ")
 
-func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Add("Vary", "Origin")
+
+		origin := c.GetHeader("Origin")
+		if origin != "" && (anyOrigin || originAllowed(config.Origins, origin)) {
+			if anyOrigin && !config.AllowCredentials {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if config.AllowCredentials && !anyOrigin {
+				c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if exposed != "" {
+				c.Writer.Header().Set("Access-Control-Expose-Headers", exposed)
+			}
+
+			if c.Request.Method == "OPTIONS" {
+				c.Writer.Header().Set("Access-Control-Allow-Headers", headers)
+				c.Writer.Header().Set("Access-Control-Allow-Methods", methods)
+				if config.MaxAge > 0 {
+					c.Writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+				}
+			}
+		}
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -18,3 +150,38 @@ func CORSMiddleware() gin.HandlerFunc {
 
 	}
 }
+
+// originAllowed matches an origin against exact entries and against
+// "scheme://*.domain" entries, which cover any subdomain of domain, and
+// "scheme://host:*" entries, which cover any port on host.
+func originAllowed(allowed []string, origin string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return false
+	}
+
+	for _, pattern := range allowed {
+		if strings.EqualFold(pattern, origin) {
+			return true
+		}
+
+		scheme, host, found := strings.Cut(pattern, "://")
+		if !found || !strings.EqualFold(scheme, parsed.Scheme) {
+			continue
+		}
+
+		if suffix, ok := strings.CutPrefix(host, "*."); ok {
+			if strings.HasSuffix(strings.ToLower(parsed.Host), "."+strings.ToLower(suffix)) {
+				return true
+			}
+		}
+
+		if hostname, ok := strings.CutSuffix(host, ":*"); ok {
+			if strings.EqualFold(parsed.Hostname(), hostname) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
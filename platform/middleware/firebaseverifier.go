@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"firebase.google.com/go/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	firebaseJWKSURL      = "https://www.googleapis.com/service_accounts/v1/jwk/securetoken@system.gserviceaccount.com"
+	firebaseIssuerPrefix = "https://securetoken.google.com/"
+)
+
+// FirebaseVerifier checks Firebase ID tokens without service account
+// credentials, using Google's published signing keys or a local fixture.
+// Unsigned Auth emulator tokens are only accepted when AllowUnsigned is set.
+type FirebaseVerifier struct {
+	ProjectID     string
+	Keys          *JWKSCache
+	AllowUnsigned bool
+}
+
+// devEnvironments are the APP_ENV values that may accept unsigned emulator
+// tokens.
+var devEnvironments = []string{"development", "dev", "test", "local"}
+
+// NewFirebaseVerifier reads the project from FIREBASE_PROJECT_ID or
+// GOOGLE_CLOUD_PROJECT. FIREBASE_JWKS_URL swaps Google's keys for another
+// JWKS, such as a file:// fixture the emulator tokens are signed with.
+// Unsigned emulator tokens need FIREBASE_AUTH_EMULATOR_HOST and
+// FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS=true, and APP_ENV set to a
+// development or test value.
+func NewFirebaseVerifier() (*FirebaseVerifier, error) {
+	projectID := os.Getenv("FIREBASE_PROJECT_ID")
+	if projectID == "" {
+		projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if projectID == "" {
+		return nil, errors.New("FIREBASE_PROJECT_ID is required without FIREBASE_CONFIG_BASE64")
+	}
+
+	jwksURL := os.Getenv("FIREBASE_JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = firebaseJWKSURL
+	}
+
+	allowUnsigned := os.Getenv("FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS") == "true"
+	if allowUnsigned {
+		if os.Getenv("FIREBASE_AUTH_EMULATOR_HOST") == "" {
+			return nil, errors.New("FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS needs FIREBASE_AUTH_EMULATOR_HOST")
+		}
+		if !slices.Contains(devEnvironments, os.Getenv("APP_ENV")) {
+			return nil, fmt.Errorf("FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS needs APP_ENV set to one of %s", strings.Join(devEnvironments, ", "))
+		}
+		log.Println("Accepting unsigned Firebase emulator tokens, anyone can forge a token.")
+	}
+
+	return &FirebaseVerifier{
+		ProjectID:     projectID,
+		Keys:          NewJWKSCache(jwksURL),
+		AllowUnsigned: allowUnsigned,
+	}, nil
+}
+
+// VerifyIDToken checks the token's signature, issuer, audience, expiry and
+// subject the way the Admin SDK does and returns it in the SDK's form.
+func (f *FirebaseVerifier) VerifyIDToken(idToken string) (*auth.Token, error) {
+	methods := []string{"RS256"}
+	if f.AllowUnsigned {
+		methods = append(methods, "none")
+	}
+
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		if f.AllowUnsigned && token.Method == jwt.SigningMethodNone {
+			return jwt.UnsafeAllowNoneSignatureType, nil
+		}
+
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("kid header not found")
+		}
+		return f.Keys.Key(kid)
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(firebaseIssuerPrefix+f.ProjectID),
+		jwt.WithAudience(f.ProjectID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" || len(sub) > 128 {
+		return nil, errors.New("ID token has an invalid subject")
+	}
+
+	iss, _ := claims["iss"].(string)
+
+	verified := &auth.Token{
+		Issuer:   iss,
+		Audience: f.ProjectID,
+		Subject:  sub,
+		UID:      sub,
+		Claims:   claims,
+	}
+
+	if info, ok := claims["firebase"].(map[string]interface{}); ok {
+		verified.Firebase.SignInProvider, _ = info["sign_in_provider"].(string)
+		verified.Firebase.Tenant, _ = info["tenant"].(string)
+	}
+
+	return verified, nil
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func fixtureVerifier(t *testing.T, keys ...testJWK) *FirebaseVerifier {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("FIREBASE_PROJECT_ID", "i9-pos-test")
+	t.Setenv("FIREBASE_JWKS_URL", "file://"+path)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", "localhost:9099")
+	t.Setenv("FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS", "")
+
+	verifier, err := NewFirebaseVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifier
+}
+
+func firebaseClaims(projectID string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": firebaseIssuerPrefix + projectID,
+		"aud": projectID,
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func unsignedToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestFirebaseVerifierFixture(t *testing.T) {
+	key := newRSAKey(t)
+	verifier := fixtureVerifier(t, rsaJWK("fixture", &key.PublicKey))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, firebaseClaims(verifier.ProjectID))
+	token.Header["kid"] = "fixture"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := verifier.VerifyIDToken(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.UID != "user-1" {
+		t.Fatalf("UID = %q, want user-1", verified.UID)
+	}
+
+	if _, err := verifier.VerifyIDToken(unsignedToken(t, firebaseClaims(verifier.ProjectID))); err == nil {
+		t.Fatal("accepted an unsigned token without FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS")
+	}
+}
+
+func TestFirebaseVerifierUnsignedOptIn(t *testing.T) {
+	fixtureVerifier(t)
+
+	t.Setenv("FIREBASE_ALLOW_UNSIGNED_EMULATOR_TOKENS", "true")
+	t.Setenv("APP_ENV", "")
+	if _, err := NewFirebaseVerifier(); err == nil {
+		t.Fatal("allowed unsigned tokens without a development APP_ENV")
+	}
+
+	t.Setenv("APP_ENV", "test")
+	verifier, err := NewFirebaseVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := verifier.VerifyIDToken(unsignedToken(t, firebaseClaims(verifier.ProjectID))); err != nil {
+		t.Fatalf("rejected an unsigned token after opting in: %v", err)
+	}
+}
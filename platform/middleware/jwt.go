@@ -1,20 +1,15 @@
 package middleware
 
 import (
-	"context"
 	"errors"
-	"net/http"
-	"os"
-	"slices"
+	"i9-pos/platform/apierror"
+	"log"
 	"strings"
 
-	firebase "firebase.google.com/go"
+	"firebase.google.com/go/auth"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
-// var ExpectedAudience = os.Getenv("AUTH0_AUDIENCE")
-
 func interfaceSliceToStringSlice(s []interface{}) ([]string, error) {
 	var stringSlice []string
 	for _, v := range s {
@@ -27,118 +22,54 @@ func interfaceSliceToStringSlice(s []interface{}) ([]string, error) {
 	return stringSlice, nil
 }
 
-func JWTAuthMiddleware(firebase *firebase.App) gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		if c.Request.Method != "POST" {
-			c.Next()
-			return
-		}
-
-		if TokenIsLocal(c) {
-			if err := ValidateLocalToken(c); err != nil {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Local token error: " + err.Error()})
-				return
-			}
-		} else {
-
-			// if !OldAuth(c) {
-			// 	return
-			// }
-			if !verifyToken(firebase, c) {
-				return
-			}
-
-		}
-		c.Next()
-	}
+func abortLocalTokenError(c *gin.Context, err error) {
+	log.Printf("Local token rejected [%s]: %v", apierror.RequestID(c), err)
+	apierror.Unauthorized(c, "Invalid or expired token")
 }
 
-func OldAuth(c *gin.Context) bool {
-	ExpectedAudience := os.Getenv("AUTH0_AUDIENCE")
+// bearerToken returns the token from the Authorization header, aborting the
+// request when there isn't one.
+func bearerToken(c *gin.Context) (string, bool) {
 
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-		return false
+		apierror.Unauthorized(c, "Authorization header required")
+		return "", false
 	}
 
 	splitToken := strings.Split(authHeader, "Bearer ")
 	if len(splitToken) != 2 {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return false
+		apierror.Unauthorized(c, "Invalid Authorization header format")
+		return "", false
 	}
 
-	tokenString := splitToken[1]
-
-	token, err := jwt.Parse(tokenString, getKeyFunc())
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token: " + err.Error()})
-		return false
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		audience := claims["aud"]
-		typed := false
-
-		if aud, ok := audience.(string); ok {
-			if aud != ExpectedAudience {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token audience"})
-				return false
-			}
-			typed = true
-		} else if aud, ok := audience.([]any); ok {
-			sliced, err := interfaceSliceToStringSlice(aud)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token audience type"})
-				return false
-			}
-			if !slices.Contains(sliced, ExpectedAudience) {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token audience"})
-				return false
-			}
-			typed = true
-		}
-
-		if !typed {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token audience type"})
-			return false
-		}
-
-	} else {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-		return false
-	}
-	return true
+	return splitToken[1], true
 }
 
-func verifyToken(app *firebase.App, c *gin.Context) bool {
+// firebaseToken verifies the bearer token with the Admin SDK client made at
+// startup, or with the offline verifier when the server runs without
+// Firebase credentials, aborting the request when it can't.
+func firebaseToken(a *Auth, c *gin.Context) (*auth.Token, bool) {
 
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-		return false
+	idToken, ok := bearerToken(c)
+	if !ok {
+		return nil, false
 	}
 
-	splitToken := strings.Split(authHeader, "Bearer ")
-	if len(splitToken) != 2 {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-		return false
-	}
+	var token *auth.Token
+	var err error
 
-	idToken := splitToken[1]
-
-	client, err := app.Auth(context.TODO())
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Error getting Auth client: " + err.Error()})
-		return false
+	if a.FirebaseClient != nil {
+		token, err = a.FirebaseClient.VerifyIDToken(c.Request.Context(), idToken)
+	} else {
+		token, err = a.FirebaseOffline.VerifyIDToken(idToken)
 	}
 
-	_, err = client.VerifyIDToken(context.TODO(), idToken)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Error verifying ID token: " + err.Error()})
-		return false
+		log.Printf("ID token rejected [%s]: %v", apierror.RequestID(c), err)
+		apierror.Unauthorized(c, "Invalid or expired token")
+		return nil, false
 	}
 
-	return true
+	return token, true
 }
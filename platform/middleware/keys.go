@@ -1,42 +1,112 @@
 package middleware
 
 import (
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/big"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-var (
-	jwksCache struct {
-		sync.RWMutex
-		keys   map[string]interface{}
-		expiry time.Time
-	}
-	// jwksURL = "https://" + os.Getenv("AUTH0_DOMAIN") + "/.well-known/jwks.json"
+const (
+	defaultJWKSTTL      = 24 * time.Hour
+	defaultJWKSCooldown = time.Minute
 )
 
-func fetchJWKS() error {
+// JWKSCache holds the signing keys published at a JWKS URL. Keys are refetched
+// once the TTL runs out, and an unknown kid triggers a refetch at most once
+// per cooldown.
+type JWKSCache struct {
+	URL      string
+	Client   *http.Client
+	TTL      time.Duration
+	Cooldown time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	expiry    time.Time
+	lastFetch time.Time
+}
+
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{
+		URL:      url,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		TTL:      defaultJWKSTTL,
+		Cooldown: defaultJWKSCooldown,
+	}
+}
+
+// Key returns the public key for kid, refetching the set when it has expired
+// or doesn't know the kid and the cooldown allows. A stale key is still used
+// when the refetch fails.
+func (j *JWKSCache) Key(kid string) (interface{}, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	fresh := time.Now().Before(j.expiry)
+	j.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := j.refresh(); err != nil {
+		if ok {
+			log.Printf("Using stale JWKS key %v: %v", kid, err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	j.mu.RLock()
+	key, ok = j.keys[kid]
+	j.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("key %v not found in JWKS", kid)
+}
 
-	// Adapt below to AWS, ideally this is all that is needed
-	// jwksURL := "https://cognito-idp." + os.Getenv("AWS_REGION") + ".amazonaws.com/" + os.Getenv("COGNITO_USER_POOL_ID") + "/.well-known/jwks.json"
+func (j *JWKSCache) refresh() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
-	jwksURL := "https://" + os.Getenv("AUTH0_DOMAIN") + "/.well-known/jwks.json"
+	if time.Since(j.lastFetch) < j.Cooldown {
+		if time.Now().Before(j.expiry) {
+			return nil
+		}
+		return errors.New("JWKS refetch is cooling down")
+	}
+	j.lastFetch = time.Now()
 
-	resp, err := http.Get(jwksURL)
+	keys, err := fetchJWKS(j.Client, j.URL)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	j.keys = keys
+	j.expiry = time.Now().Add(j.TTL)
+
+	return nil
+}
+
+// fetchJWKS loads a key set over HTTP, or from disk for a file:// URL.
+func fetchJWKS(client *http.Client, jwksURL string) (map[string]interface{}, error) {
+
+	body, err := readJWKS(client, jwksURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var jwks struct {
@@ -51,59 +121,72 @@ func fetchJWKS() error {
 	}
 
 	if err := json.Unmarshal(body, &jwks); err != nil {
-		return err
+		return nil, err
 	}
 
 	newKeys := make(map[string]interface{})
 	for _, key := range jwks.Keys {
-		if key.Kty == "RSA" {
-			parsedKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte("-----BEGIN CERTIFICATE-----\n" + key.X5c[0] + "\n-----END CERTIFICATE-----"))
-			if err != nil {
-				continue // or log the error
-			}
-			newKeys[key.Kid] = parsedKey
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
 		}
-	}
 
-	jwksCache.Lock()
-	jwksCache.keys = newKeys
-	jwksCache.expiry = time.Now().Add(24 * time.Hour) // Cache for 24 hours
-	jwksCache.Unlock()
+		var parsedKey *rsa.PublicKey
+		var err error
 
-	return nil
-}
-
-func getKeyFunc() jwt.Keyfunc {
-	return func(token *jwt.Token) (interface{}, error) {
-		// Ensure the token algorithm matches your expectations
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if key.N != "" && key.E != "" {
+			parsedKey, err = rsaKeyFromModulus(key.N, key.E)
+		} else if len(key.X5c) > 0 {
+			parsedKey, err = jwt.ParseRSAPublicKeyFromPEM([]byte("-----BEGIN CERTIFICATE-----\n" + key.X5c[0] + "\n-----END CERTIFICATE-----"))
+		} else {
+			err = errors.New("no n/e or x5c")
 		}
 
-		kid, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, fmt.Errorf("kid header not found")
+		if err != nil {
+			log.Printf("Skipping JWKS key %v: %v", key.Kid, err)
+			continue
 		}
+		newKeys[key.Kid] = parsedKey
+	}
 
-		jwksCache.RLock()
-		key, ok := jwksCache.keys[kid]
-		jwksCache.RUnlock()
-		if ok && key != nil {
-			return key, nil
-		}
+	return newKeys, nil
+}
 
-		// Refresh JWKS if the key is not found (may handle differently based on your needs)
-		if err := fetchJWKS(); err != nil {
-			return nil, err
-		}
+func readJWKS(client *http.Client, jwksURL string) ([]byte, error) {
+	if path, found := strings.CutPrefix(jwksURL, "file://"); found {
+		return os.ReadFile(path)
+	}
 
-		jwksCache.RLock()
-		key, ok = jwksCache.keys[kid]
-		jwksCache.RUnlock()
-		if ok && key != nil {
-			return key, nil
-		}
+	resp, err := client.Get(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS fetch returned status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func rsaKeyFromModulus(n, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil, fmt.Errorf("key %v not found in JWKS", kid)
+	exponent := new(big.Int).SetBytes(eBytes)
+	if !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
 	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(exponent.Int64()),
+	}, nil
 }
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testJWK map[string]interface{}
+
+// jwksServer serves whatever keys currently holds and counts the fetches.
+type jwksServer struct {
+	*httptest.Server
+	keys    atomic.Value
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...testJWK) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{}
+	s.keys.Store(keys)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys.Load()})
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) testJWK {
+	return testJWK{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestJWKSCacheModulusKey(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("a", &key.PublicKey))
+
+	got, err := NewJWKSCache(server.URL).Key("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, ok := got.(*rsa.PublicKey)
+	if !ok || !pub.Equal(&key.PublicKey) {
+		t.Fatalf("Key(a) = %v, want the served public key", got)
+	}
+}
+
+func TestJWKSCacheTTLExpiry(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("a", &key.PublicKey))
+
+	cache := NewJWKSCache(server.URL)
+	cache.TTL = 50 * time.Millisecond
+	cache.Cooldown = 0
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Key("a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := server.fetches.Load(); n != 1 {
+		t.Fatalf("fetches before expiry = %d, want 1", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if _, err := cache.Key("a"); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("fetches after expiry = %d, want 2", n)
+	}
+}
+
+func TestJWKSCacheUnknownKidCooldown(t *testing.T) {
+	keyA, keyB := newRSAKey(t), newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("a", &keyA.PublicKey))
+
+	cache := NewJWKSCache(server.URL)
+	cache.Cooldown = 100 * time.Millisecond
+
+	if _, err := cache.Key("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Key("b"); err == nil {
+			t.Fatal("Key(b) succeeded before b was published")
+		}
+	}
+	if n := server.fetches.Load(); n != 1 {
+		t.Fatalf("fetches during cooldown = %d, want 1", n)
+	}
+
+	server.keys.Store([]testJWK{rsaJWK("a", &keyA.PublicKey), rsaJWK("b", &keyB.PublicKey)})
+	time.Sleep(110 * time.Millisecond)
+
+	if _, err := cache.Key("b"); err != nil {
+		t.Fatalf("Key(b) after cooldown: %v", err)
+	}
+	if n := server.fetches.Load(); n != 2 {
+		t.Fatalf("fetches after cooldown = %d, want 2", n)
+	}
+}
+
+func TestJWKSCacheEmptyX5c(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t,
+		testJWK{"kty": "RSA", "kid": "empty", "x5c": []string{}},
+		testJWK{"kty": "RSA", "kid": "blank", "x5c": []string{""}},
+		rsaJWK("a", &key.PublicKey),
+	)
+
+	cache := NewJWKSCache(server.URL)
+
+	for _, kid := range []string{"empty", "blank"} {
+		if _, err := cache.Key(kid); err == nil {
+			t.Errorf("Key(%s) succeeded for a key without material", kid)
+		}
+	}
+
+	if _, err := cache.Key("a"); err != nil {
+		t.Fatalf("Key(a) next to the broken keys: %v", err)
+	}
+}
+
+func TestOIDCProviderClaims(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, rsaJWK("a", &key.PublicKey))
+
+	provider := &OIDCProvider{
+		Issuer:   "https://issuer.test/",
+		Audience: "i9-pos",
+		Keys:     NewJWKSCache(server.URL),
+	}
+
+	sign := func(claims jwt.MapClaims, kid string, signer *rsa.PrivateKey) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	valid := jwt.MapClaims{
+		"iss": provider.Issuer,
+		"aud": provider.Audience,
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	claims, err := provider.Claims(sign(valid, "a", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "user-1" {
+		t.Fatalf("sub = %v, want user-1", claims["sub"])
+	}
+
+	wrongAudience := jwt.MapClaims{"iss": provider.Issuer, "aud": "other", "sub": "user-1", "exp": valid["exp"]}
+	if _, err := provider.Claims(sign(wrongAudience, "a", key)); err == nil {
+		t.Error("accepted a token for another audience")
+	}
+
+	if _, err := provider.Claims(sign(valid, "a", newRSAKey(t))); err == nil {
+		t.Error("accepted a token signed by an unpublished key")
+	}
+}
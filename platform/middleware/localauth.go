@@ -4,49 +4,114 @@ import (
 	"errors"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+type localClaims struct {
+	Scope  *string `json:"scope,omitempty"`
+	Tenant string  `json:"tenant,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateJWT issues a local token for id that expires after LOCAL_TOKEN_TTL
+// and is addressed to LOCAL_AUDIENCE when that is set.
 func GenerateJWT(id string) (string, error) {
+	tokenString, _, err := GenerateScopedJWT(id, nil, "")
+	return tokenString, err
+}
+
+// GenerateScopedJWT issues a local token like GenerateJWT with the scopes in
+// a space separated "scope" claim and, unless empty, the tenant in a "tenant"
+// claim. The scope claim is left out only for nil scopes, so an empty list
+// gives a token with no scopes rather than the defaults. It also returns the
+// token's lifetime.
+func GenerateScopedJWT(id string, scopes []string, tenant string) (string, time.Duration, error) {
 
 	localIssuer := os.Getenv("LOCAL_ISSUER")
 
-	claims := &jwt.RegisteredClaims{
-		Issuer:  localIssuer,
-		Subject: id,
+	ttl, err := localTokenTTL()
+	if err != nil {
+		return "", 0, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key, err := localSigningKey()
+	if err != nil {
+		return "", 0, err
+	}
+
+	now := time.Now()
+
+	claims := &localClaims{
+		Tenant: tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    localIssuer,
+			Subject:   id,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	if scopes != nil {
+		scope := strings.Join(scopes, " ")
+		claims.Scope = &scope
+	}
 
-	mySigningKey := []byte(os.Getenv("LOCAL_KEY"))
+	if audience := os.Getenv("LOCAL_AUDIENCE"); audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if key.kid != "" {
+		token.Header["kid"] = key.kid
+	}
 
-	tokenString, err := token.SignedString(mySigningKey)
+	tokenString, err := token.SignedString(key.secret)
 
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 
-	return tokenString, nil
+	return tokenString, ttl, nil
 }
 
 func ValidateLocalToken(c *gin.Context) error {
+	_, err := localTokenClaims(c)
+	return err
+}
+
+// localTokenClaims verifies the bearer token as a locally issued token and
+// returns its claims.
+func localTokenClaims(c *gin.Context) (jwt.MapClaims, error) {
 
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		return jwt.ErrInvalidType
+		return nil, jwt.ErrInvalidType
 	}
 
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return jwt.ErrInvalidType
+		return nil, jwt.ErrInvalidType
 	}
 
 	tokenString := parts[1]
 
 	localIssuer := os.Getenv("LOCAL_ISSUER")
-	mySigningKey := []byte(os.Getenv("LOCAL_KEY"))
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithIssuedAt(),
+	}
+
+	if os.Getenv("LOCAL_ALLOW_NO_EXP") != "true" {
+		options = append(options, jwt.WithExpirationRequired())
+	}
+
+	if audience := os.Getenv("LOCAL_AUDIENCE"); audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 
@@ -54,32 +119,34 @@ func ValidateLocalToken(c *gin.Context) error {
 			return nil, errors.New("Unexpected signing method: " + token.Header["alg"].(string))
 		}
 
-		return mySigningKey, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+
+		return localVerificationKey(kid)
+	}, options...)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return jwt.ErrInvalidKey
+		return nil, jwt.ErrInvalidKey
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return jwt.ErrTokenInvalidClaims
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
 	iss, ok := claims["iss"].(string)
 	if !ok {
-		return jwt.ErrTokenInvalidClaims
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
 	if iss != localIssuer {
-		return jwt.ErrTokenInvalidIssuer
+		return nil, jwt.ErrTokenInvalidIssuer
 	}
 
-	return nil
+	return claims, nil
 }
 
 func TokenIsLocal(c *gin.Context) bool {
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultLocalTokenTTL = time.Hour
+
+type localKey struct {
+	kid    string
+	secret []byte
+}
+
+// localKeys reads the active local signing keys. LOCAL_KEYS lists them as
+// comma separated kid:secret pairs, the first being the signing key unless
+// LOCAL_SIGNING_KID names another. LOCAL_KEY is still accepted as the key for
+// tokens without a kid, so old tokens keep working during a rotation.
+func localKeys() ([]localKey, error) {
+	keys := []localKey{}
+
+	for _, pair := range strings.Split(os.Getenv("LOCAL_KEYS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		kid, secret, found := strings.Cut(pair, ":")
+		if !found || kid == "" || secret == "" {
+			return nil, errors.New("LOCAL_KEYS entries must be kid:secret")
+		}
+
+		keys = append(keys, localKey{kid: kid, secret: []byte(secret)})
+	}
+
+	if legacy := os.Getenv("LOCAL_KEY"); legacy != "" {
+		keys = append(keys, localKey{kid: "", secret: []byte(legacy)})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no local signing keys configured")
+	}
+
+	return keys, nil
+}
+
+func localSigningKey() (localKey, error) {
+	keys, err := localKeys()
+	if err != nil {
+		return localKey{}, err
+	}
+
+	signingKid := os.Getenv("LOCAL_SIGNING_KID")
+	if signingKid == "" {
+		return keys[0], nil
+	}
+
+	for _, key := range keys {
+		if key.kid == signingKid {
+			return key, nil
+		}
+	}
+
+	return localKey{}, errors.New("LOCAL_SIGNING_KID is not one of LOCAL_KEYS")
+}
+
+func localVerificationKey(kid string) ([]byte, error) {
+	keys, err := localKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.kid == kid {
+			return key.secret, nil
+		}
+	}
+
+	return nil, errors.New("unknown local key id " + kid)
+}
+
+// localTokenTTL is the lifetime of issued local tokens, set with
+// LOCAL_TOKEN_TTL as a Go duration.
+func localTokenTTL() (time.Duration, error) {
+	ttl := os.Getenv("LOCAL_TOKEN_TTL")
+	if ttl == "" {
+		return defaultLocalTokenTTL, nil
+	}
+
+	parsed, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, err
+	}
+	if parsed <= 0 {
+		return 0, errors.New("LOCAL_TOKEN_TTL must be positive")
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"i9-pos/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware records every request's status and latency against its
+// route pattern, so /samples/:id is one series however many IDs are asked
+// for. Requests that match no route are recorded as "unmatched".
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.Request(c.Request.Method, route, c.Writer.Status(), start)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"i9-pos/platform/apierror"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Policy string
+
+const (
+	Public        Policy = "public"
+	Authenticated Policy = "authenticated"
+	Admin         Policy = "admin"
+)
+
+func (p Policy) Valid() bool {
+	return p == Public || p == Authenticated || p == Admin
+}
+
+// RequirePolicy returns the auth middleware for a route's policy and the scope
+// the route declares. Public routes pass straight through. Authenticated
+// routes need a token holding the scope, and admin routes need the admin role
+// as well as the scope.
+func RequirePolicy(auth *Auth, policy Policy, scope string) gin.HandlerFunc {
+	if policy == Public {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	if policy == Admin && scope == "" {
+		return AdminMiddleware(auth)
+	}
+
+	return func(c *gin.Context) {
+		principal, ok := authenticate(auth, c)
+		if !ok {
+			return
+		}
+
+		if scope != "" && !principal.HasScope(scope) {
+			log.Printf("Scope %s denied for %q (%s): %s %s", scope, principal.UID, principal.Provider, c.Request.Method, c.Request.URL.Path)
+			apierror.Forbidden(c, "Missing scope "+scope)
+			return
+		}
+
+		if policy == Admin && !hasAdminRole(principal.Claims) {
+			log.Printf("Admin access denied for %q (%s): %s %s", principal.UID, principal.Provider, c.Request.Method, c.Request.URL.Path)
+			apierror.Forbidden(c, "Admin role required")
+			return
+		}
+
+		if policy == Admin {
+			log.Printf("Admin access by %q (%s): %s %s", principal.UID, principal.Provider, c.Request.Method, c.Request.URL.Path)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"firebase.google.com/go/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const principalKey = "principal"
+
+// Principal is the verified identity behind a request, whichever token type
+// it came from.
+type Principal struct {
+	UID      string
+	Issuer   string
+	Provider string
+	Local    bool
+	Scopes   []string
+	Tenant   string
+	Claims   map[string]interface{}
+}
+
+// GetPrincipal returns the identity the auth middleware verified for the
+// request, if any.
+func GetPrincipal(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := v.(Principal)
+	return principal, ok
+}
+
+func SetPrincipal(c *gin.Context, principal Principal) {
+	c.Set(principalKey, principal)
+}
+
+// authenticate verifies the bearer token as a local token or with the
+// configured provider and stores the resulting principal on the context,
+// aborting the request when verification fails.
+func authenticate(auth *Auth, c *gin.Context) (Principal, bool) {
+	var principal Principal
+
+	if TokenIsLocal(c) {
+		claims, err := localTokenClaims(c)
+		if err != nil {
+			abortLocalTokenError(c, err)
+			return Principal{}, false
+		}
+		principal = localPrincipal(claims)
+	} else if auth.Provider == "oidc" {
+		claims, ok := oidcToken(auth.OIDC, c)
+		if !ok {
+			return Principal{}, false
+		}
+		principal = oidcPrincipal(claims)
+	} else {
+		token, ok := firebaseToken(auth, c)
+		if !ok {
+			return Principal{}, false
+		}
+		principal = firebasePrincipal(token)
+	}
+
+	SetPrincipal(c, principal)
+
+	return principal, true
+}
+
+func localPrincipal(claims jwt.MapClaims) Principal {
+	uid, _ := claims["sub"].(string)
+	iss, _ := claims["iss"].(string)
+
+	return Principal{
+		UID:      uid,
+		Issuer:   iss,
+		Provider: "local",
+		Local:    true,
+		Scopes:   claimScopes(claims),
+		Tenant:   claimTenant(claims),
+		Claims:   claims,
+	}
+}
+
+func firebasePrincipal(token *auth.Token) Principal {
+	return Principal{
+		UID:      token.UID,
+		Issuer:   token.Issuer,
+		Provider: token.Firebase.SignInProvider,
+		Local:    false,
+		Scopes:   claimScopes(token.Claims),
+		Tenant:   claimTenant(token.Claims),
+		Claims:   token.Claims,
+	}
+}
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"encoding/json"
+	"i9-pos/platform/apierror"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.etcd.io/bbolt"
+)
+
+const rateLimitBucketName = "RateLimitBucket"
+
+// RateLimit allows Requests per Per, refilled continuously, with bursts up to
+// Requests.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+type TokenBucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// RateLimitStore keeps token buckets. Update applies fn to the bucket for key
+// atomically and saves the result.
+type RateLimitStore interface {
+	Update(key string, fn func(TokenBucket) TokenBucket) error
+}
+
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]TokenBucket
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]TokenBucket{}}
+}
+
+func (m *MemoryRateLimitStore) Update(key string, fn func(TokenBucket) TokenBucket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.buckets[key] = fn(m.buckets[key])
+
+	if len(m.buckets) > 10000 {
+		for k, b := range m.buckets {
+			if time.Since(b.Updated) > time.Hour {
+				delete(m.buckets, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// BoltRateLimitStore keeps buckets in bbolt so limits survive a restart. A
+// bucket idle for longer than MaxIdle has refilled, so those are pruned at
+// most once a minute to keep the store bounded.
+type BoltRateLimitStore struct {
+	DB      *bbolt.DB
+	MaxIdle time.Duration
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewBoltRateLimitStore(db *bbolt.DB, maxIdle time.Duration) *BoltRateLimitStore {
+	return &BoltRateLimitStore{DB: db, MaxIdle: maxIdle, lastPrune: time.Now()}
+}
+
+func (s *BoltRateLimitStore) Update(key string, fn func(TokenBucket) TokenBucket) error {
+	err := s.DB.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(rateLimitBucketName))
+		if err != nil {
+			return err
+		}
+
+		var bucket TokenBucket
+		if v := b.Get([]byte(key)); v != nil {
+			if err := json.Unmarshal(v, &bucket); err != nil {
+				log.Printf("Failed to unmarshal rate limit bucket %s: %v, resetting it", key, err)
+				bucket = TokenBucket{}
+			}
+		}
+
+		data, err := json.Marshal(fn(bucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(key), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.pruneDue() {
+		if err := s.prune(); err != nil {
+			log.Printf("Failed to prune rate limit buckets: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *BoltRateLimitStore) pruneDue() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastPrune) < time.Minute {
+		return false
+	}
+	s.lastPrune = time.Now()
+	return true
+}
+
+// prune deletes the buckets that haven't been touched for MaxIdle, along
+// with any that can't be read.
+func (s *BoltRateLimitStore) prune() error {
+	cutoff := time.Now().Add(-s.MaxIdle)
+
+	return s.DB.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(rateLimitBucketName))
+		if b == nil {
+			return nil
+		}
+
+		stale := [][]byte{}
+		err := b.ForEach(func(k, v []byte) error {
+			var bucket TokenBucket
+			if err := json.Unmarshal(v, &bucket); err != nil || bucket.Updated.Before(cutoff) {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RateLimitMiddleware limits each caller of a route, identified by the
+// verified principal or by client IP when there is none. The client IP only
+// comes from forwarding headers sent by the engine's trusted proxies. It sets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers and
+// answers 429 with Retry-After once the bucket is empty. Store errors let
+// the request through.
+func RateLimitMiddleware(store RateLimitStore, limit RateLimit) gin.HandlerFunc {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Per.Seconds()
+
+	return func(c *gin.Context) {
+
+		caller := "ip:" + c.ClientIP()
+		if principal, ok := GetPrincipal(c); ok && principal.UID != "" {
+			caller = "principal:" + principal.Provider + ":" + principal.UID
+		}
+		key := c.Request.Method + " " + c.FullPath() + " " + caller
+
+		var allowed bool
+		var tokens float64
+
+		err := store.Update(key, func(bucket TokenBucket) TokenBucket {
+			now := time.Now()
+
+			if bucket.Updated.IsZero() {
+				bucket.Tokens = capacity
+			} else {
+				bucket.Tokens = math.Min(capacity, bucket.Tokens+now.Sub(bucket.Updated).Seconds()*rate)
+			}
+			bucket.Updated = now
+
+			allowed = bucket.Tokens >= 1
+			if allowed {
+				bucket.Tokens--
+			}
+			tokens = bucket.Tokens
+
+			return bucket
+		})
+		if err != nil {
+			log.Printf("Rate limit store error for %s: %v", key, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(int(math.Floor(tokens))))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil((capacity-tokens)/rate))))
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil((1-tokens)/rate))))
+			apierror.RateLimited(c)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeWorkoutsGenerate = "workouts:generate"
+	ScopeSamplesRead      = "samples:read"
+	ScopeCatalogWrite     = "catalog:write"
+	ScopeCacheAdmin       = "cache:admin"
+	ScopeMetricsRead      = "metrics:read"
+
+	// ScopeTenantsAdmin lets a token bound to no tenant pick one with
+	// X-Tenant-ID on admin routes. The admin role doesn't grant it, it has to
+	// be given explicitly.
+	ScopeTenantsAdmin = "tenants:admin"
+)
+
+// AllScopes are granted to callers with the admin role.
+var AllScopes = []string{ScopeWorkoutsGenerate, ScopeSamplesRead, ScopeCatalogWrite, ScopeCacheAdmin, ScopeMetricsRead}
+
+// DefaultScopes are granted to tokens that carry no scope claim, which keeps
+// existing app users and older local tokens working.
+var DefaultScopes = []string{ScopeWorkoutsGenerate, ScopeSamplesRead}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// claimScopes works out a token's scopes. A space separated "scope" claim or a
+// "scopes" list claim sets them exactly, otherwise the defaults apply. Roles
+// from the "role" and "roles" claims add the scopes ROLE_SCOPES maps them to,
+// and the admin role grants every scope in AllScopes.
+func claimScopes(claims map[string]interface{}) []string {
+	scopes := DefaultScopes
+
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = strings.Fields(scope)
+	} else if scopeList, ok := claims["scopes"].([]interface{}); ok {
+		if sliced, err := interfaceSliceToStringSlice(scopeList); err == nil {
+			scopes = sliced
+		}
+	}
+
+	roleScopes := roleScopeMap()
+	for _, role := range claimRoles(claims) {
+		scopes = append(scopes, roleScopes[role]...)
+	}
+
+	if hasAdminRole(claims) {
+		scopes = append(scopes, AllScopes...)
+	}
+
+	return uniqueStrings(scopes)
+}
+
+func claimRoles(claims map[string]interface{}) []string {
+	roles := []string{}
+
+	if role, ok := claims["role"].(string); ok {
+		roles = append(roles, role)
+	}
+
+	if roleList, ok := claims["roles"].([]interface{}); ok {
+		if sliced, err := interfaceSliceToStringSlice(roleList); err == nil {
+			roles = append(roles, sliced...)
+		}
+	}
+
+	return roles
+}
+
+// roleScopeMap reads ROLE_SCOPES, a JSON object from role name to the scopes
+// that role grants.
+func roleScopeMap() map[string][]string {
+	roleScopes := map[string][]string{}
+
+	raw := os.Getenv("ROLE_SCOPES")
+	if raw == "" {
+		return roleScopes
+	}
+
+	if err := json.Unmarshal([]byte(raw), &roleScopes); err != nil {
+		log.Printf("Ignoring invalid ROLE_SCOPES: %v", err)
+		return map[string][]string{}
+	}
+
+	return roleScopes
+}
+
+func uniqueStrings(sl []string) []string {
+	ret := []string{}
+	for _, s := range sl {
+		if !slices.Contains(ret, s) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"i9-pos/database"
+	"i9-pos/platform/apierror"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tenantKey = "tenant"
+
+// TenantHeader picks a tenant for callers whose token doesn't name one
+const TenantHeader = "X-Tenant-ID"
+
+// KnownTenants lists the tenants database.Tenants reads from TENANTS, so the
+// middleware accepts exactly the tenants the database was set up for.
+func KnownTenants() map[string]bool {
+	tenants := map[string]bool{}
+	for _, tenant := range database.Tenants() {
+		tenants[tenant] = true
+	}
+	return tenants
+}
+
+// claimTenant reads the tenant from the claim TENANT_CLAIM names, "tenant"
+// by default.
+func claimTenant(claims map[string]interface{}) string {
+	name := os.Getenv("TENANT_CLAIM")
+	if name == "" {
+		name = "tenant"
+	}
+
+	tenant, _ := claims[name].(string)
+	return tenant
+}
+
+// TenantMiddleware resolves which tenant's catalog a request uses. A tenant
+// in the verified token wins, and a header naming a different one is
+// refused. Otherwise the X-Tenant-ID header is used, and without either the
+// request gets the default tenant. On /admin/ routes a token bound to no
+// tenant may only name another tenant with the tenants:admin scope, so
+// reading and generating is all an unbound token can do across tenants.
+func TenantMiddleware(tenants map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := c.GetHeader(TenantHeader)
+
+		principal, authenticated := GetPrincipal(c)
+
+		if authenticated && principal.Tenant != "" {
+			if tenant != "" && tenant != principal.Tenant {
+				log.Printf("Tenant %q denied for %q (%s), token is bound to %q", tenant, principal.UID, principal.Provider, principal.Tenant)
+				apierror.Forbidden(c, "Token is not valid for tenant "+tenant)
+				return
+			}
+			tenant = principal.Tenant
+		} else if tenant != "" && strings.HasPrefix(c.FullPath(), "/admin/") && !principal.HasScope(ScopeTenantsAdmin) {
+			log.Printf("Tenant %q denied for %q (%s), token is bound to no tenant: %s %s", tenant, principal.UID, principal.Provider, c.Request.Method, c.Request.URL.Path)
+			apierror.Forbidden(c, "Token is not bound to tenant "+tenant+" and lacks scope "+ScopeTenantsAdmin)
+			return
+		}
+
+		if !tenants[tenant] {
+			apierror.BadRequest(c, "Unknown tenant "+tenant, nil)
+			return
+		}
+
+		SetTenant(c, tenant)
+
+		c.Next()
+	}
+}
+
+// GetTenant returns the tenant TenantMiddleware resolved for the request,
+// the default tenant if it didn't run.
+func GetTenant(c *gin.Context) string {
+	return c.GetString(tenantKey)
+}
+
+func SetTenant(c *gin.Context, tenant string) {
+	c.Set(tenantKey, tenant)
+}
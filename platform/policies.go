@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"i9-pos/platform/middleware"
+	"log"
+	"os"
+	"strings"
+)
+
+// defaultPolicies holds the auth policy of every route keyed by
+// "METHOD path". It keeps the original behaviour: generation needs a token,
+// reads are open and admin routes need the admin role. /metrics and
+// DELETE /admin/cache only need their scope, so scrapers and the
+// catalog-archive command can use client tokens.
+var defaultPolicies = map[string]middleware.Policy{
+	"GET /":                                   middleware.Public,
+	"POST /auth/token":                        middleware.Public,
+	"GET /samples":                            middleware.Public,
+	"GET /samples/:id":                        middleware.Public,
+	"GET /samples/ext/:type/:id":              middleware.Public,
+	"GET /themes":                             middleware.Public,
+	"POST /workouts/stretch":                  middleware.Authenticated,
+	"POST /workouts":                          middleware.Authenticated,
+	"DELETE /clearcache":                      middleware.Public,
+	"DELETE /admin/cache":                     middleware.Authenticated,
+	"GET /metrics":                            middleware.Authenticated,
+	"GET /admin/exercises":                    middleware.Admin,
+	"GET /admin/exercises/:id":                middleware.Admin,
+	"POST /admin/exercises":                   middleware.Admin,
+	"PUT /admin/exercises/:id":                middleware.Admin,
+	"PATCH /admin/exercises/:id":              middleware.Admin,
+	"DELETE /admin/exercises/:id":             middleware.Admin,
+	"GET /admin/dynamics":                     middleware.Admin,
+	"GET /admin/dynamics/:id":                 middleware.Admin,
+	"POST /admin/dynamics":                    middleware.Admin,
+	"PUT /admin/dynamics/:id":                 middleware.Admin,
+	"PATCH /admin/dynamics/:id":               middleware.Admin,
+	"DELETE /admin/dynamics/:id":              middleware.Admin,
+	"GET /admin/statics":                      middleware.Admin,
+	"GET /admin/statics/:id":                  middleware.Admin,
+	"POST /admin/statics":                     middleware.Admin,
+	"PUT /admin/statics/:id":                  middleware.Admin,
+	"PATCH /admin/statics/:id":                middleware.Admin,
+	"DELETE /admin/statics/:id":               middleware.Admin,
+	"GET /admin/samples/report":               middleware.Admin,
+	"POST /admin/samples":                     middleware.Admin,
+	"PUT /admin/samples/:id":                  middleware.Admin,
+	"DELETE /admin/samples/:id":               middleware.Admin,
+	"GET /admin/themes":                       middleware.Admin,
+	"GET /admin/themes/:id":                   middleware.Admin,
+	"POST /admin/themes":                      middleware.Admin,
+	"PUT /admin/themes/:id":                   middleware.Admin,
+	"DELETE /admin/themes/:id":                middleware.Admin,
+	"PUT /admin/settings/default-theme":       middleware.Admin,
+	"GET /admin/transitions/history":          middleware.Admin,
+	"GET /admin/transitions/:speed/:from/:to": middleware.Admin,
+	"PUT /admin/transitions/:speed/:from/:to": middleware.Admin,
+}
+
+// profileOverrides lists, per AUTH_PROFILE, the routes whose policy differs
+// from the defaults. The strict profile closes the public reads and the
+// legacy password cache route.
+var profileOverrides = map[string]map[string]middleware.Policy{
+	"default": {},
+	"strict": {
+		"GET /samples":               middleware.Authenticated,
+		"GET /samples/:id":           middleware.Authenticated,
+		"GET /samples/ext/:type/:id": middleware.Authenticated,
+		"GET /themes":                middleware.Authenticated,
+		"DELETE /clearcache":         middleware.Admin,
+	},
+}
+
+// routePolicies picks the profile named by AUTH_PROFILE and applies any
+// "METHOD path=policy" overrides listed in ROUTE_POLICIES, separated by
+// semicolons. Routes missing from the table need a token.
+func routePolicies() func(method, path string) middleware.Policy {
+	profileName := os.Getenv("AUTH_PROFILE")
+	if profileName == "" {
+		profileName = "default"
+	}
+
+	profile, ok := profileOverrides[profileName]
+	if !ok {
+		log.Fatalf("Unknown AUTH_PROFILE %q", profileName)
+	}
+
+	policies := map[string]middleware.Policy{}
+	for route, policy := range defaultPolicies {
+		policies[route] = policy
+	}
+	for route, policy := range profile {
+		policies[route] = policy
+	}
+
+	for _, override := range strings.Split(os.Getenv("ROUTE_POLICIES"), ";") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+
+		route, policy, found := strings.Cut(override, "=")
+		if !found || !middleware.Policy(strings.TrimSpace(policy)).Valid() {
+			log.Fatalf("Invalid ROUTE_POLICIES entry %q", override)
+		}
+
+		policies[strings.Join(strings.Fields(route), " ")] = middleware.Policy(strings.TrimSpace(policy))
+	}
+
+	return func(method, path string) middleware.Policy {
+		if policy, ok := policies[method+" "+path]; ok {
+			return policy
+		}
+		return middleware.Authenticated
+	}
+}
@@ -0,0 +1,100 @@
+package platform
+
+import (
+	"errors"
+	"i9-pos/platform/middleware"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// routeLimits holds the default rate limit of each limited route keyed by
+// "METHOD path". Routes missing from the table aren't limited.
+var routeLimits = map[string]middleware.RateLimit{
+	"POST /auth/token":       {Requests: 10, Per: time.Minute},
+	"POST /workouts":         {Requests: 30, Per: time.Minute},
+	"POST /workouts/stretch": {Requests: 60, Per: time.Minute},
+}
+
+// rateLimits applies any "METHOD path=requests/duration" overrides listed in
+// RATE_LIMITS, separated by semicolons, with 0 requests removing a limit. The
+// buckets live in memory unless RATE_LIMIT_STORE is "bolt", where buckets
+// idle for longer than the longest limit period, and at least an hour, are
+// pruned.
+func rateLimits(boltDB *bbolt.DB) (func(method, path string) (middleware.RateLimit, bool), middleware.RateLimitStore) {
+	limits := map[string]middleware.RateLimit{}
+	for route, limit := range routeLimits {
+		limits[route] = limit
+	}
+
+	for _, override := range strings.Split(os.Getenv("RATE_LIMITS"), ";") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+
+		route, value, found := strings.Cut(override, "=")
+		if !found {
+			log.Fatalf("Invalid RATE_LIMITS entry %q", override)
+		}
+
+		limit, err := parseRateLimit(value)
+		if err != nil {
+			log.Fatalf("Invalid RATE_LIMITS entry %q: %v", override, err)
+		}
+
+		limits[strings.Join(strings.Fields(route), " ")] = limit
+	}
+
+	var store middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if os.Getenv("RATE_LIMIT_STORE") == "bolt" {
+		maxIdle := time.Hour
+		for _, limit := range limits {
+			maxIdle = max(maxIdle, limit.Per)
+		}
+		store = middleware.NewBoltRateLimitStore(boltDB, maxIdle)
+	}
+
+	return func(method, path string) (middleware.RateLimit, bool) {
+		limit, ok := limits[method+" "+path]
+		return limit, ok && limit.Requests > 0
+	}, store
+}
+
+// trustedProxies reads TRUSTED_PROXIES, a comma separated list of proxy IPs
+// or CIDRs whose X-Forwarded-For headers are believed. By default none are,
+// so callers can't pick their own rate limit key.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func parseRateLimit(value string) (middleware.RateLimit, error) {
+	errFormat := errors.New("limits must look like 30/1m")
+
+	requests, per, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return middleware.RateLimit{}, errFormat
+	}
+
+	count, err := strconv.Atoi(requests)
+	if err != nil || count < 0 {
+		return middleware.RateLimit{}, errFormat
+	}
+
+	duration, err := time.ParseDuration(per)
+	if err != nil || duration <= 0 {
+		return middleware.RateLimit{}, errFormat
+	}
+
+	return middleware.RateLimit{Requests: count, Per: duration}, nil
+}
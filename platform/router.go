@@ -1,12 +1,17 @@
 package platform
 
 import (
-	"errors"
+	"fmt"
+	"i9-pos/admin"
+	"i9-pos/database"
 	"i9-pos/gets"
+	"i9-pos/metrics"
+	"i9-pos/platform/apierror"
 	"i9-pos/platform/middleware"
 	"i9-pos/posts"
 	"log"
 	"net/http"
+	"os"
 
 	firebase "firebase.google.com/go"
 	"github.com/gin-gonic/gin"
@@ -22,25 +27,107 @@ type PasswordRequest struct {
 }
 
 func New(database *mongo.Database, firebase *firebase.App, boltDB *bbolt.DB) *gin.Engine {
-	router := gin.Default()
+	prom := metrics.NewPrometheus()
+	metrics.Set(prom)
 
-	router.Use(middleware.CORSMiddleware())
-	router.Use(middleware.JWTAuthMiddleware(firebase))
+	router := gin.New()
 
-	router.GET("/", temp())
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	router.Use(middleware.RequestIDMiddleware(), middleware.MetricsMiddleware(), gin.Logger(), gin.CustomRecovery(recovered))
+	router.Use(middleware.CORSMiddleware(middleware.LoadCORSConfig()))
+
+	router.NoRoute(func(c *gin.Context) {
+		apierror.NotFound(c, "No route matches "+c.Request.Method+" "+c.Request.URL.Path)
+	})
+
+	auth, err := middleware.NewAuth(firebase)
+	if err != nil {
+		log.Fatalf("Error configuring auth: %v", err)
+	}
+
+	policy := routePolicies()
+	limit, limitStore := rateLimits(boltDB)
+	tenant := middleware.TenantMiddleware(middleware.KnownTenants())
+
+	handle := func(method, path, scope string, handler gin.HandlerFunc) {
+		handlers := []gin.HandlerFunc{middleware.RequirePolicy(auth, policy(method, path), scope), tenant}
+		if rateLimit, ok := limit(method, path); ok {
+			handlers = append(handlers, middleware.RateLimitMiddleware(limitStore, rateLimit))
+		}
+		router.Handle(method, path, append(handlers, handler)...)
+	}
+
+	handle("GET", "/", "", temp())
+
+	handle("POST", "/auth/token", "", issueToken(tokenClients()))
+
+	handle("GET", "/samples", middleware.ScopeSamplesRead, gets.GetSamples(database, boltDB))
+	handle("GET", "/samples/:id", middleware.ScopeSamplesRead, gets.GetSampleByID(database, boltDB))
+	handle("GET", "/samples/ext/:type/:id", middleware.ScopeSamplesRead, gets.GetSampleByExtID(database, boltDB))
+
+	handle("GET", "/themes", middleware.ScopeSamplesRead, gets.GetThemes(database, boltDB))
 
-	router.GET("/samples", gets.GetSamples(database, boltDB))
-	router.GET("/samples/:id", gets.GetSampleByID(database, boltDB))
-	router.GET("/samples/ext/:type/:id", gets.GetSampleByExtID(database, boltDB))
+	handle("POST", "/workouts/stretch", middleware.ScopeWorkoutsGenerate, posts.PostStretchWorkout(database, boltDB))
+	handle("POST", "/workouts", middleware.ScopeWorkoutsGenerate, posts.PostWorkout(database, boltDB))
 
-	router.POST("/workouts/stretch", posts.PostStretchWorkout(database, boltDB))
-	router.POST("/workouts", posts.PostWorkout(database, boltDB))
+	if hash := os.Getenv("CLEARCACHE_PASSWORD_HASH"); hash != "" {
+		handle("DELETE", "/clearcache", middleware.ScopeCacheAdmin, passwordClearcache(boltDB, hash))
+	}
+
+	handle("DELETE", "/admin/cache", middleware.ScopeCacheAdmin, clearcache(boltDB))
+
+	handle("GET", "/metrics", middleware.ScopeMetricsRead, gin.WrapH(prom.Handler()))
+
+	handle("GET", "/admin/exercises", middleware.ScopeCatalogWrite, admin.GetExercises(database))
+	handle("GET", "/admin/exercises/:id", middleware.ScopeCatalogWrite, admin.GetExercise(database))
+	handle("POST", "/admin/exercises", middleware.ScopeCatalogWrite, admin.PostExercise(database, boltDB))
+	handle("PUT", "/admin/exercises/:id", middleware.ScopeCatalogWrite, admin.PutExercise(database, boltDB))
+	handle("PATCH", "/admin/exercises/:id", middleware.ScopeCatalogWrite, admin.PatchExercise(database, boltDB))
+	handle("DELETE", "/admin/exercises/:id", middleware.ScopeCatalogWrite, admin.DeleteExercise(database, boltDB))
+
+	handle("GET", "/admin/dynamics", middleware.ScopeCatalogWrite, admin.GetDynamics(database))
+	handle("GET", "/admin/dynamics/:id", middleware.ScopeCatalogWrite, admin.GetDynamic(database))
+	handle("POST", "/admin/dynamics", middleware.ScopeCatalogWrite, admin.PostDynamic(database, boltDB))
+	handle("PUT", "/admin/dynamics/:id", middleware.ScopeCatalogWrite, admin.PutDynamic(database, boltDB))
+	handle("PATCH", "/admin/dynamics/:id", middleware.ScopeCatalogWrite, admin.PatchDynamic(database, boltDB))
+	handle("DELETE", "/admin/dynamics/:id", middleware.ScopeCatalogWrite, admin.DeleteDynamic(database, boltDB))
+
+	handle("GET", "/admin/statics", middleware.ScopeCatalogWrite, admin.GetStatics(database))
+	handle("GET", "/admin/statics/:id", middleware.ScopeCatalogWrite, admin.GetStatic(database))
+	handle("POST", "/admin/statics", middleware.ScopeCatalogWrite, admin.PostStatic(database, boltDB))
+	handle("PUT", "/admin/statics/:id", middleware.ScopeCatalogWrite, admin.PutStatic(database, boltDB))
+	handle("PATCH", "/admin/statics/:id", middleware.ScopeCatalogWrite, admin.PatchStatic(database, boltDB))
+	handle("DELETE", "/admin/statics/:id", middleware.ScopeCatalogWrite, admin.DeleteStatic(database, boltDB))
+
+	handle("GET", "/admin/samples/report", middleware.ScopeCatalogWrite, admin.GetSampleReport(database))
+	handle("POST", "/admin/samples", middleware.ScopeCatalogWrite, admin.PostSample(database, boltDB))
+	handle("PUT", "/admin/samples/:id", middleware.ScopeCatalogWrite, admin.PutSample(database, boltDB))
+	handle("DELETE", "/admin/samples/:id", middleware.ScopeCatalogWrite, admin.DeleteSample(database, boltDB))
 
-	router.DELETE("/clearcache", clearcache(boltDB))
+	handle("GET", "/admin/themes", middleware.ScopeCatalogWrite, admin.GetThemes(database))
+	handle("GET", "/admin/themes/:id", middleware.ScopeCatalogWrite, admin.GetTheme(database))
+	handle("POST", "/admin/themes", middleware.ScopeCatalogWrite, admin.PostTheme(database, boltDB))
+	handle("PUT", "/admin/themes/:id", middleware.ScopeCatalogWrite, admin.PutTheme(database, boltDB))
+	handle("DELETE", "/admin/themes/:id", middleware.ScopeCatalogWrite, admin.DeleteTheme(database, boltDB))
+
+	handle("PUT", "/admin/settings/default-theme", middleware.ScopeCatalogWrite, admin.PutDefaultTheme(database, boltDB))
+
+	handle("GET", "/admin/transitions/history", middleware.ScopeCatalogWrite, admin.GetTransitionHistory(database))
+	handle("GET", "/admin/transitions/:speed/:from/:to", middleware.ScopeCatalogWrite, admin.GetTransition(database))
+	handle("PUT", "/admin/transitions/:speed/:from/:to", middleware.ScopeCatalogWrite, admin.PutTransition(database, boltDB))
 
 	return router
 }
 
+// recovered answers a handler panic with the error envelope instead of an
+// empty 500.
+func recovered(c *gin.Context, err interface{}) {
+	apierror.Internal(c, "Internal error", fmt.Errorf("panic: %v", err))
+}
+
 func temp() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -49,42 +136,88 @@ func temp() gin.HandlerFunc {
 	}
 }
 
-func clearcache(db *bbolt.DB) gin.HandlerFunc {
+// passwordClearcache keeps the legacy password guarded route for callers that
+// haven't moved to /admin/cache. It is only registered when
+// CLEARCACHE_PASSWORD_HASH holds a bcrypt hash, and flushes every tenant's
+// cache as it always has.
+func passwordClearcache(db *bbolt.DB, hash string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var req PasswordRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.BadRequest(c, "Issue with body binding", err.Error())
 			return
 		}
 
-		err := bcrypt.CompareHashAndPassword([]byte("$2a$10$cvD77jlhjwTXKLkX.KeI0Ool7Kp5HCjovhJ.mX01P18qxt4R/CbIu"), []byte(req.Password))
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.New("password doesn't match").Error()})
+			apierror.Unauthorized(c, "Password doesn't match")
+			return
+		}
+
+		// Firebase email sign-ins report "password" as their provider, so
+		// this route logs under its own name.
+		middleware.SetPrincipal(c, middleware.Principal{Provider: "legacy-password"})
+
+		if err := flushCache(db); err != nil {
+			apierror.Internal(c, "Issue with clearing cache", err)
 			return
 		}
 
-		err = db.Update(func(tx *bbolt.Tx) error {
-			b := tx.Bucket([]byte(bucketName))
-			if b == nil {
-				return nil
+		cacheCleared(c, "every tenant")
+	}
+}
+
+// clearcache drops the request tenant's cache. With ?all=true a token
+// holding tenants:admin flushes every tenant's.
+func clearcache(db *bbolt.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		if c.Query("all") == "true" {
+			if principal, _ := middleware.GetPrincipal(c); !principal.HasScope(middleware.ScopeTenantsAdmin) {
+				apierror.Forbidden(c, "Missing scope "+middleware.ScopeTenantsAdmin)
+				return
 			}
-			return b.ForEach(func(k, v []byte) error {
-				return b.Delete(k)
-			})
-		})
 
-		if err != nil {
-			log.Printf("Failed to clear cache: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"message": "error clearing cache",
-			})
+			if err := flushCache(db); err != nil {
+				apierror.Internal(c, "Issue with clearing cache", err)
+				return
+			}
+
+			cacheCleared(c, "every tenant")
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message": "success",
-		})
+		tenant := middleware.GetTenant(c)
+
+		if err := database.ClearTenantCache(db, tenant); err != nil {
+			apierror.Internal(c, "Issue with clearing cache", err)
+			return
+		}
+
+		cacheCleared(c, fmt.Sprintf("tenant %q", tenant))
 	}
 }
+
+// flushCache empties the whole CacheBucket.
+func flushCache(db *bbolt.DB) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return b.Delete(k)
+		})
+	})
+}
+
+func cacheCleared(c *gin.Context, whose string) {
+	principal, _ := middleware.GetPrincipal(c)
+	log.Printf("Cache of %s cleared by %q (%s)", whose, principal.UID, principal.Provider)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "success",
+	})
+}
@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"encoding/json"
+	"i9-pos/platform/middleware"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TokenClient is a service allowed to mint local tokens through the client
+// credentials flow. Tokens for a client with a Tenant are bound to that
+// tenant's catalog.
+type TokenClient struct {
+	ID         string   `json:"id"`
+	SecretHash string   `json:"secret_hash"`
+	Scopes     []string `json:"scopes"`
+	Tenant     string   `json:"tenant"`
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" json:"grant_type" binding:"required"`
+	ClientID     string `form:"client_id" json:"client_id"`
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+	Scope        string `form:"scope" json:"scope"`
+}
+
+// tokenClients reads AUTH_CLIENTS, a JSON list of clients with bcrypt hashed
+// secrets and the scopes each may request. A client must list at least one
+// scope.
+func tokenClients() map[string]TokenClient {
+	clients := map[string]TokenClient{}
+
+	raw := os.Getenv("AUTH_CLIENTS")
+	if raw == "" {
+		return clients
+	}
+
+	var clientList []TokenClient
+	if err := json.Unmarshal([]byte(raw), &clientList); err != nil {
+		log.Fatalf("Invalid AUTH_CLIENTS: %v", err)
+	}
+
+	for _, client := range clientList {
+		if client.ID == "" || client.SecretHash == "" {
+			log.Fatalf("Invalid AUTH_CLIENTS: every client needs an id and secret_hash")
+		}
+		if len(client.Scopes) == 0 {
+			log.Fatalf("Invalid AUTH_CLIENTS: client %q has no scopes", client.ID)
+		}
+		clients[client.ID] = client
+	}
+
+	return clients
+}
+
+// issueToken implements the OAuth2 client credentials grant. Credentials are
+// taken from HTTP basic auth or the request body, and the token's scopes are
+// the requested ones or, when none are asked for, all the client's scopes.
+func issueToken(clients map[string]TokenClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var req TokenRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+			return
+		}
+
+		if req.GrantType != "client_credentials" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+			return
+		}
+
+		if id, secret, ok := c.Request.BasicAuth(); ok {
+			req.ClientID, req.ClientSecret = id, secret
+		}
+
+		client, ok := clients[req.ClientID]
+		if !ok || bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(req.ClientSecret)) != nil {
+			log.Printf("Rejected token request for client %q", req.ClientID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+			return
+		}
+
+		scopes := client.Scopes
+		if req.Scope != "" {
+			scopes = strings.Fields(req.Scope)
+			for _, scope := range scopes {
+				if !slices.Contains(client.Scopes, scope) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_scope"})
+					return
+				}
+			}
+		}
+
+		token, ttl, err := middleware.GenerateScopedJWT(client.ID, scopes, client.Tenant)
+		if err != nil {
+			log.Printf("Failed to issue token for client %q: %v", client.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_in":   int(ttl.Seconds()),
+			"scope":        strings.Join(scopes, " "),
+		})
+	}
+}
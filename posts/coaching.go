@@ -0,0 +1,81 @@
+package posts
+
+import (
+	"fmt"
+	"i9-pos/datatypes"
+	"math"
+	"sort"
+)
+
+// CoachingScript turns a timeline into the spoken cues a text-to-speech
+// client pre-renders: countdowns out of rests, side switches, halfway and
+// last rep calls, rest lengths and what comes next.
+func CoachingScript(timeline datatypes.Timeline) []datatypes.CoachingCue {
+	script := []datatypes.CoachingCue{}
+
+	for i, block := range timeline.Blocks {
+		if block.Kind == "Rest" {
+			script = append(script, restCues(timeline.Blocks, i)...)
+			continue
+		}
+
+		if i == 0 || timeline.Blocks[i-1].Kind != "Rest" {
+			script = append(script, datatypes.CoachingCue{Start: block.Start, Kind: "start", Text: block.Name})
+		} else {
+			script = append(script, datatypes.CoachingCue{Start: block.Start, Kind: "go", Text: "Go"})
+		}
+
+		switched := false
+		for _, rep := range block.Reps {
+			if rep.SwitchSides {
+				switched = true
+				script = append(script, datatypes.CoachingCue{Start: rep.Start, Kind: "switch", Text: "Switch sides"})
+			}
+
+			if rep.Transition {
+				script = append(script, datatypes.CoachingCue{Start: rep.Start, Kind: "nextup", Text: "Next up: " + rep.Name})
+			}
+
+			if block.Kind != "Static" && rep.Of > 1 && rep.Count == rep.Of {
+				script = append(script, datatypes.CoachingCue{Start: rep.Start, Kind: "lastrep", Text: "Last rep"})
+			}
+		}
+
+		if !switched && block.FullTime >= 10 {
+			script = append(script, datatypes.CoachingCue{Start: block.Start + block.FullTime/2, Kind: "halfway", Text: "Halfway"})
+		}
+	}
+
+	sort.SliceStable(script, func(i, j int) bool {
+		return script[i].Start < script[j].Start
+	})
+
+	return script
+}
+
+// restCues announces a rest, names the block after it when the rest is long
+// enough to fit it in, and counts down the last three seconds.
+func restCues(blocks []datatypes.TimelineBlock, i int) []datatypes.CoachingCue {
+	rest := blocks[i]
+	secs := int(math.Round(float64(rest.FullTime)))
+
+	text := fmt.Sprintf("Rest for %d seconds", secs)
+	if secs == 1 {
+		text = "Rest for 1 second"
+	}
+
+	cues := []datatypes.CoachingCue{{Start: rest.Start, Kind: "rest", Text: text}}
+
+	if i+1 < len(blocks) && blocks[i+1].Kind != "Rest" && rest.FullTime >= 8 {
+		cues = append(cues, datatypes.CoachingCue{Start: rest.Start + rest.FullTime/2, Kind: "nextup", Text: "Next up: " + blocks[i+1].Name})
+	}
+
+	if rest.FullTime >= 4 {
+		end := rest.Start + rest.FullTime
+		for count := 3; count > 0; count-- {
+			cues = append(cues, datatypes.CoachingCue{Start: end - float32(count), Kind: "countdown", Text: fmt.Sprint(count)})
+		}
+	}
+
+	return cues
+}
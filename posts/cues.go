@@ -0,0 +1,79 @@
+package posts
+
+import (
+	"fmt"
+	"i9-pos/datatypes"
+	"math"
+	"strings"
+)
+
+// TimelineCues turns every rep and rest of a timeline into a display cue
+// carrying the exercise name and rep counter.
+func TimelineCues(timeline datatypes.Timeline) []datatypes.Cue {
+	cues := []datatypes.Cue{}
+
+	for _, block := range timeline.Blocks {
+		for _, rep := range block.Reps {
+			if rep.FullTime <= 0 {
+				continue
+			}
+
+			lines := []string{rep.Name}
+
+			if block.Kind == "Rest" {
+				lines = []string{"Rest"}
+			} else if rep.Transition {
+				lines = []string{"Next up: " + rep.Name}
+			} else if block.Kind != "Static" && rep.Of > 0 {
+				lines = append(lines, fmt.Sprintf("Rep %d/%d", rep.Count, rep.Of))
+			}
+
+			if rep.SwitchSides {
+				lines = append(lines, "Switch sides")
+			}
+
+			cues = append(cues, datatypes.Cue{
+				Start: rep.Start,
+				End:   rep.Start + rep.FullTime,
+				Text:  strings.Join(lines, "\n"),
+			})
+		}
+	}
+
+	return cues
+}
+
+func WebVTT(cues []datatypes.Cue) string {
+	var sb strings.Builder
+
+	sb.WriteString("WEBVTT\n\n")
+
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, cueTimestamp(cue.Start, "."), cueTimestamp(cue.End, "."), cue.Text)
+	}
+
+	return sb.String()
+}
+
+func SRT(cues []datatypes.Cue) string {
+	var sb strings.Builder
+
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, cueTimestamp(cue.Start, ","), cueTimestamp(cue.End, ","), cue.Text)
+	}
+
+	return sb.String()
+}
+
+func cueTimestamp(secs float32, sep string) string {
+	millis := int64(math.Round(float64(secs) * 1000))
+	if millis < 0 {
+		millis = 0
+	}
+
+	hours := millis / 3600000
+	minutes := (millis / 60000) % 60
+	seconds := (millis / 1000) % 60
+
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, sep, millis%1000)
+}
@@ -0,0 +1,61 @@
+package posts
+
+import (
+	"i9-pos/datatypes"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// imageCDNBase reads IMAGE_CDN_BASE once, warning at startup when it isn't
+// set.
+var imageCDNBase = sync.OnceValue(func() string {
+	cdnBase := os.Getenv("IMAGE_CDN_BASE")
+	if cdnBase == "" {
+		log.Println("IMAGE_CDN_BASE is not set, preload manifests will list image sets without URLs.")
+	}
+	return cdnBase
+})
+
+// PreloadManifest lists each image set in the order the timeline first shows
+// it, with the time it is first needed and a URL for every resolution tier
+// under the CDN base. Without a CDN base the URLs are left out.
+func PreloadManifest(timeline datatypes.Timeline, cdnBase string) []datatypes.PreloadImage {
+	manifest := []datatypes.PreloadImage{}
+	seen := map[string]bool{}
+
+	for _, block := range timeline.Blocks {
+		for _, rep := range block.Reps {
+			for _, frame := range rep.Frames {
+				if frame.Position == "" || seen[frame.Position] {
+					continue
+				}
+				seen[frame.Position] = true
+				manifest = append(manifest, preloadImage(frame.Position, frame.Start, cdnBase))
+			}
+		}
+	}
+
+	return manifest
+}
+
+func preloadImage(id string, firstNeeded float32, cdnBase string) datatypes.PreloadImage {
+	if cdnBase == "" {
+		return datatypes.PreloadImage{
+			ImageSetID:  id,
+			FirstNeeded: firstNeeded,
+		}
+	}
+
+	base := strings.TrimSuffix(cdnBase, "/") + "/" + id + "/"
+
+	return datatypes.PreloadImage{
+		ImageSetID:  id,
+		FirstNeeded: firstNeeded,
+		Low:         base + "low",
+		Mid:         base + "mid",
+		High:        base + "high",
+		Original:    base + "original",
+	}
+}
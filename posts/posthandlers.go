@@ -1,7 +1,11 @@
 package posts
 
 import (
+	"errors"
+	"i9-pos/database"
 	"i9-pos/datatypes"
+	"i9-pos/platform/apierror"
+	"i9-pos/platform/middleware"
 
 	"github.com/gin-gonic/gin"
 	"go.etcd.io/bbolt"
@@ -9,23 +13,23 @@ import (
 )
 
 func PostStretchWorkout(database *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	cdnBase := imageCDNBase()
+
 	return func(c *gin.Context) {
 
 		var strWOBody datatypes.StretchWorkoutRoute
 		if err := c.ShouldBindJSON(&strWOBody); err != nil {
-			c.JSON(400, gin.H{
-				"Error": "Issue with body binding",
-				"Exact": err.Error(),
-			})
+			apierror.BadRequest(c, "Issue with body binding", err.Error())
 			return
 		}
 
-		stretchWO, err := StretchWorkout(database, boltDB, strWOBody)
+		stretchWO, err := StretchWorkout(database, boltDB, middleware.GetTenant(c), strWOBody)
 		if err != nil {
-			c.JSON(400, gin.H{
-				"Error": "Issue with stretch WO creation",
-				"Exact": err.Error(),
-			})
+			writeWorkoutError(c, "Issue with stretch WO creation", err)
+			return
+		}
+
+		if writeCues(c, StretchTimeline(stretchWO), cdnBase) {
 			return
 		}
 
@@ -40,23 +44,23 @@ func PostStretchWorkout(database *mongo.Database, boltDB *bbolt.DB) gin.HandlerF
 }
 
 func PostWorkout(database *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
+	cdnBase := imageCDNBase()
+
 	return func(c *gin.Context) {
 
 		var WOBody datatypes.WorkoutRoute
 		if err := c.ShouldBindJSON(&WOBody); err != nil {
-			c.JSON(400, gin.H{
-				"Error": "Issue with body binding",
-				"Exact": err.Error(),
-			})
+			apierror.BadRequest(c, "Issue with body binding", err.Error())
 			return
 		}
 
-		workout, err := Workout(database, boltDB, WOBody)
+		workout, err := Workout(database, boltDB, middleware.GetTenant(c), WOBody)
 		if err != nil {
-			c.JSON(400, gin.H{
-				"Error": "Issue with WO creation",
-				"Exact": err.Error(),
-			})
+			writeWorkoutError(c, "Issue with WO creation", err)
+			return
+		}
+
+		if writeCues(c, WorkoutTimeline(workout), cdnBase) {
 			return
 		}
 
@@ -70,68 +74,124 @@ func PostWorkout(database *mongo.Database, boltDB *bbolt.DB) gin.HandlerFunc {
 	}
 }
 
+// writeWorkoutError answers 400 for a request missing a part of the workout,
+// 422 for IDs the catalog doesn't hold and 503 when the catalog can't be
+// loaded.
+func writeWorkoutError(c *gin.Context, message string, err error) {
+	var unresolved *UnresolvedIDsError
+
+	switch {
+	case errors.Is(err, ErrIncompleteWorkout):
+		apierror.Validation(c, []string{err.Error()})
+	case errors.As(err, &unresolved):
+		apierror.Unresolvable(c, message, unresolved)
+	default:
+		apierror.Unavailable(c, message, err)
+	}
+}
+
+// writeCues answers with a WebVTT or SRT cue track, a coaching script or an
+// image preload manifest when the format query parameter asks for one, and
+// reports whether it wrote a response.
+func writeCues(c *gin.Context, timeline datatypes.Timeline, cdnBase string) bool {
+	switch c.Query("format") {
+	case "", "json":
+		return false
+	case "vtt":
+		c.Data(200, "text/vtt; charset=utf-8", []byte(WebVTT(TimelineCues(timeline))))
+	case "srt":
+		c.Data(200, "application/x-subrip; charset=utf-8", []byte(SRT(TimelineCues(timeline))))
+	case "script":
+		c.JSON(200, gin.H{
+			"script":   CoachingScript(timeline),
+			"fulltime": timeline.FullTime,
+		})
+	case "manifest":
+		c.JSON(200, gin.H{
+			"manifest": PreloadManifest(timeline, cdnBase),
+			"fulltime": timeline.FullTime,
+		})
+	default:
+		apierror.BadRequest(c, "Issue with format", "format must be one of json, vtt, srt, script or manifest")
+	}
+	return true
+}
+
+// uniqueIMGsStr lists the stretch workout's image sets in the order they are
+// first shown, starting with the standing position and ending with the
+// congrats position.
 func uniqueIMGsStr(strWO datatypes.StretchWorkout) []string {
-	imgMap := map[string]bool{}
-	ret := []string{}
+	imgs := []string{strWO.StandingPosition}
+
+	imgs = append(imgs, timelineIMGs(StretchTimeline(strWO))...)
 
 	for _, set := range strWO.DynamicSlice {
-		for _, rep := range set.RepSlice {
-			for _, pos := range rep.Positions {
-				imgMap[pos] = true
-			}
-		}
+		imgs = append(imgs, setIMGs(set)...)
 	}
 
 	for _, set := range strWO.StaticSlice {
-		for _, rep := range set.RepSlice {
-			for _, pos := range rep.Positions {
-				imgMap[pos] = true
-			}
-		}
+		imgs = append(imgs, setIMGs(set)...)
 	}
 
-	for img := range imgMap {
-		ret = append(ret, img)
-	}
-
-	return ret
+	imgs = append(imgs, strWO.CongratsPosition)
 
+	return nonEmpty(database.UniqueStrSlice(imgs))
 }
 
+// uniqueIMGsWO lists the workout's image sets in the order they are first
+// shown, starting with the standing position, including every round's rest
+// position and ending with the congrats position.
 func uniqueIMGsWO(WO datatypes.Workout) []string {
-	imgMap := map[string]bool{}
-	ret := []string{}
+	imgs := []string{WO.StandingPosition}
+
+	imgs = append(imgs, timelineIMGs(WorkoutTimeline(WO))...)
 
 	for _, set := range WO.DynamicSlice {
-		for _, rep := range set.RepSlice {
-			for _, pos := range rep.Positions {
-				imgMap[pos] = true
-			}
+		imgs = append(imgs, setIMGs(set)...)
+	}
+
+	for _, round := range WO.Exercises {
+		for _, set := range round.SetSlice {
+			imgs = append(imgs, setIMGs(set)...)
 		}
+		imgs = append(imgs, round.RestPosition)
 	}
 
 	for _, set := range WO.StaticSlice {
-		for _, rep := range set.RepSlice {
-			for _, pos := range rep.Positions {
-				imgMap[pos] = true
-			}
-		}
+		imgs = append(imgs, setIMGs(set)...)
 	}
 
-	for _, round := range WO.Exercises {
-		for _, set := range round.SetSlice {
-			for _, rep := range set.RepSlice {
-				for _, pos := range rep.Positions {
-					imgMap[pos] = true
-				}
+	imgs = append(imgs, WO.CongratsPosition)
+
+	return nonEmpty(database.UniqueStrSlice(imgs))
+}
+
+func timelineIMGs(timeline datatypes.Timeline) []string {
+	imgs := []string{}
+	for _, block := range timeline.Blocks {
+		for _, rep := range block.Reps {
+			for _, frame := range rep.Frames {
+				imgs = append(imgs, frame.Position)
 			}
 		}
 	}
+	return imgs
+}
 
-	for img := range imgMap {
-		ret = append(ret, img)
+func setIMGs(set datatypes.Set) []string {
+	imgs := []string{}
+	for _, rep := range set.RepSlice {
+		imgs = append(imgs, rep.Positions...)
 	}
+	return imgs
+}
 
+func nonEmpty(sl []string) []string {
+	ret := []string{}
+	for _, s := range sl {
+		if s != "" {
+			ret = append(ret, s)
+		}
+	}
 	return ret
-
 }
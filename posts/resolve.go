@@ -0,0 +1,59 @@
+package posts
+
+import (
+	"errors"
+	"fmt"
+	"i9-pos/database"
+	"i9-pos/metrics"
+)
+
+var ErrIncompleteWorkout = errors.New("workout needs at least one dynamic, static and exercise")
+
+// UnresolvedIDsError lists the IDs in a workout request that the tenant's
+// catalog doesn't hold.
+type UnresolvedIDsError struct {
+	Dynamics  []string `json:"dynamics,omitempty"`
+	Statics   []string `json:"statics,omitempty"`
+	Exercises []string `json:"exercises,omitempty"`
+	Theme     string   `json:"theme,omitempty"`
+}
+
+func (e *UnresolvedIDsError) Error() string {
+	return fmt.Sprintf("unresolved ids: dynamics %v, statics %v, exercises %v, theme %q", e.Dynamics, e.Statics, e.Exercises, e.Theme)
+}
+
+// record counts the unresolved IDs by kind.
+func (e *UnresolvedIDsError) record() {
+	metrics.UnknownIDs("dynamic", len(e.Dynamics))
+	metrics.UnknownIDs("static", len(e.Statics))
+	metrics.UnknownIDs("exercise", len(e.Exercises))
+	if e.Theme != "" {
+		metrics.UnknownIDs("theme", 1)
+	}
+}
+
+func (e *UnresolvedIDsError) empty() bool {
+	return len(e.Dynamics) == 0 && len(e.Statics) == 0 && len(e.Exercises) == 0 && e.Theme == ""
+}
+
+// missingIDs lists the requested IDs the query didn't return, each once.
+func missingIDs[T any](found map[string]T, ids []string) []string {
+	missing := []string{}
+	for _, id := range database.UniqueStrSlice(ids) {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// themeError reports an unknown theme as an unresolved ID and passes any
+// other error through.
+func themeError(id string, err error) error {
+	if errors.Is(err, database.ErrUnknownTheme) {
+		unresolved := &UnresolvedIDsError{Theme: id}
+		unresolved.record()
+		return unresolved
+	}
+	return err
+}
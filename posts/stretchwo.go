@@ -1,26 +1,47 @@
 package posts
 
 import (
-	"errors"
 	"i9-pos/database"
 	"i9-pos/datatypes"
+	"i9-pos/metrics"
 	"math"
+	"time"
 
 	"go.etcd.io/bbolt"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func StretchWorkout(db *mongo.Database, boltDB *bbolt.DB, strWOBody datatypes.StretchWorkoutRoute) (datatypes.StretchWorkout, error) {
+func StretchWorkout(db *mongo.Database, boltDB *bbolt.DB, tenant string, strWOBody datatypes.StretchWorkoutRoute) (datatypes.StretchWorkout, error) {
+	defer metrics.WorkoutGeneration("stretch", time.Now())
 
 	retWO := datatypes.StretchWorkout{}
 
-	dynamics, statics, err := database.QueryStretchWO(db, boltDB, strWOBody.Statics, strWOBody.Dynamics)
+	if len(strWOBody.Dynamics) == 0 || len(strWOBody.Statics) == 0 {
+		return datatypes.StretchWorkout{}, ErrIncompleteWorkout
+	}
+
+	dynamics, statics, err := database.QueryStretchWO(db, boltDB, tenant, strWOBody.Statics, strWOBody.Dynamics)
+	if err != nil {
+		return datatypes.StretchWorkout{}, err
+	}
+
+	unresolved := &UnresolvedIDsError{
+		Dynamics: missingIDs(dynamics, strWOBody.Dynamics),
+		Statics:  missingIDs(statics, strWOBody.Statics),
+	}
+	if !unresolved.empty() {
+		unresolved.record()
+		return datatypes.StretchWorkout{}, unresolved
+	}
+
+	settings, err := database.GetTenantSettings(db, boltDB, tenant)
 	if err != nil {
 		return datatypes.StretchWorkout{}, err
 	}
 
-	if len(dynamics) == 0 || len(statics) == 0 {
-		return datatypes.StretchWorkout{}, errors.New("unfilled dynamic/static/imagesets returned")
+	theme, err := database.ResolveTheme(db, boltDB, tenant, strWOBody.ThemeID, settings)
+	if err != nil {
+		return datatypes.StretchWorkout{}, themeError(strWOBody.ThemeID, err)
 	}
 
 	dynamicSets, dynamicNames, dynamicSamples := DynamicSets(dynamics, strWOBody.Dynamics, strWOBody.StretchTimes)
@@ -35,8 +56,9 @@ func StretchWorkout(db *mongo.Database, boltDB *bbolt.DB, strWOBody datatypes.St
 
 	retWO.RoundTime = strWOBody.StretchTimes.FullRound / 2
 
-	retWO.CongratsPosition = "standing-thumbs-up-wink"
-	retWO.StandingPosition = "standing-arms-bent"
+	retWO.CongratsPosition = theme.CongratsPosition
+	retWO.StandingPosition = theme.StandingPosition
+	retWO.CatalogVersion = settings.CatalogVersion
 
 	retWO.BackendID = strWOBody.ID.Hex()
 
@@ -0,0 +1,184 @@
+package posts
+
+import (
+	"i9-pos/datatypes"
+	"strings"
+)
+
+// WorkoutTimeline lays a generated workout out in playback order: dynamic
+// stretches, the dynamic rest, each exercise round with its set and round
+// rests, then the static stretches.
+func WorkoutTimeline(workout datatypes.Workout) datatypes.Timeline {
+	timeline := datatypes.Timeline{Blocks: []datatypes.TimelineBlock{}}
+
+	for i, set := range workout.DynamicSlice {
+		name := nameAt(workout.DynamicNames, i)
+		addSetBlock(&timeline, "Dynamic", name, -1, set, []string{name}, []int{set.RepCount})
+	}
+
+	addRestBlock(&timeline, workout.DynamicRest, workout.Exercises[0].RestPosition, -1)
+
+	for r, round := range workout.Exercises {
+		for i, setIndex := range round.SetSequence {
+			if setIndex >= len(round.SetSlice) {
+				continue
+			}
+			set := round.SetSlice[setIndex]
+
+			if round.Type == "Combo" {
+				addSetBlock(&timeline, "Exercise", roundTitle(round), r, set, round.Names, round.Reps)
+			} else {
+				addSetBlock(&timeline, "Exercise", roundTitle(round), r, set, []string{roundTitle(round)}, []int{set.RepCount})
+			}
+
+			if i != len(round.SetSequence)-1 {
+				addRestBlock(&timeline, round.RestPerSet, round.RestPosition, r)
+			}
+		}
+
+		if r != len(workout.Exercises)-1 {
+			addRestBlock(&timeline, round.RestPerRound, round.RestPosition, r)
+		}
+	}
+
+	for i, set := range workout.StaticSlice {
+		name := nameAt(workout.StaticNames, i)
+		addSetBlock(&timeline, "Static", name, -1, set, []string{name}, []int{set.RepCount})
+	}
+
+	return timeline
+}
+
+// StretchTimeline lays a stretch workout out as its dynamic stretches
+// followed by its static stretches.
+func StretchTimeline(strWO datatypes.StretchWorkout) datatypes.Timeline {
+	timeline := datatypes.Timeline{Blocks: []datatypes.TimelineBlock{}}
+
+	for i, set := range strWO.DynamicSlice {
+		name := nameAt(strWO.DynamicNames, i)
+		addSetBlock(&timeline, "Dynamic", name, -1, set, []string{name}, []int{set.RepCount})
+	}
+
+	for i, set := range strWO.StaticSlice {
+		name := nameAt(strWO.StaticNames, i)
+		addSetBlock(&timeline, "Static", name, -1, set, []string{name}, []int{set.RepCount})
+	}
+
+	return timeline
+}
+
+// addSetBlock walks a set's rep sequence. Combo sets pass one name and rep
+// count per exercise, and the rep following each exercise's last rep is the
+// transition into the next exercise.
+func addSetBlock(timeline *datatypes.Timeline, kind, name string, round int, set datatypes.Set, names []string, counts []int) {
+	block := datatypes.TimelineBlock{
+		Kind:  kind,
+		Name:  name,
+		Round: round,
+		Start: timeline.FullTime,
+		Reps:  []datatypes.TimelineRep{},
+	}
+
+	current, count := 0, 0
+	start := block.Start
+
+	for i, repIndex := range set.RepSequence {
+		if repIndex >= len(set.RepSlice) {
+			continue
+		}
+		rep := set.RepSlice[repIndex]
+
+		timelineRep := datatypes.TimelineRep{
+			Start:    start,
+			FullTime: rep.FullTime,
+			Frames:   repFrames(rep, start),
+		}
+
+		if current < len(counts) && count == counts[current] && current < len(names)-1 {
+			current++
+			count = 0
+			timelineRep.Name = names[current]
+			timelineRep.Transition = true
+		} else {
+			count++
+			timelineRep.Name = nameAt(names, current)
+			timelineRep.Count = count
+			if current < len(counts) {
+				timelineRep.Of = counts[current]
+			}
+		}
+
+		if set.SeparateStretch && i > 0 && repIndex != set.RepSequence[i-1] {
+			timelineRep.SwitchSides = true
+		}
+
+		block.Reps = append(block.Reps, timelineRep)
+		start += rep.FullTime
+	}
+
+	block.FullTime = start - block.Start
+
+	timeline.Blocks = append(timeline.Blocks, block)
+	timeline.FullTime = start
+}
+
+func addRestBlock(timeline *datatypes.Timeline, restTime float32, restPosition string, round int) {
+	if restTime <= 0 {
+		return
+	}
+
+	start := timeline.FullTime
+
+	rep := datatypes.TimelineRep{
+		Name:     "Rest",
+		Start:    start,
+		FullTime: restTime,
+		Frames: []datatypes.TimelineFrame{{
+			Position: restPosition,
+			Start:    start,
+			Duration: restTime,
+		}},
+	}
+
+	timeline.Blocks = append(timeline.Blocks, datatypes.TimelineBlock{
+		Kind:     "Rest",
+		Name:     "Rest",
+		Round:    round,
+		Start:    start,
+		FullTime: restTime,
+		Reps:     []datatypes.TimelineRep{rep},
+	})
+	timeline.FullTime = start + restTime
+}
+
+func repFrames(rep datatypes.Rep, start float32) []datatypes.TimelineFrame {
+	frames := []datatypes.TimelineFrame{}
+
+	for i, position := range rep.Positions {
+		if i >= len(rep.Times) {
+			break
+		}
+		frames = append(frames, datatypes.TimelineFrame{
+			Position: position,
+			Start:    start,
+			Duration: rep.Times[i],
+		})
+		start += rep.Times[i]
+	}
+
+	return frames
+}
+
+func roundTitle(round datatypes.WORound) string {
+	if round.Type == "Combo" {
+		return strings.Join(round.Names, " + ")
+	}
+	return strings.Join(round.Names, " / ")
+}
+
+func nameAt(names []string, i int) string {
+	if i < len(names) {
+		return names[i]
+	}
+	return ""
+}
@@ -1,30 +1,55 @@
 package posts
 
 import (
-	"errors"
 	"i9-pos/database"
 	"i9-pos/datatypes"
+	"i9-pos/metrics"
 	"math"
+	"time"
 
 	"go.etcd.io/bbolt"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func Workout(db *mongo.Database, boltDB *bbolt.DB, WOBody datatypes.WorkoutRoute) (datatypes.Workout, error) {
+func Workout(db *mongo.Database, boltDB *bbolt.DB, tenant string, WOBody datatypes.WorkoutRoute) (datatypes.Workout, error) {
+	defer metrics.WorkoutGeneration("workout", time.Now())
+
 	workout := datatypes.Workout{}
 
 	exerIDRoundList := [9][]string{}
+	exerIDs := []string{}
 	for i, workoutRound := range WOBody.Exercises {
 		exerIDRoundList[i] = workoutRound.ExerciseIDs
+		exerIDs = append(exerIDs, workoutRound.ExerciseIDs...)
+	}
+
+	if len(WOBody.Dynamics) == 0 || len(WOBody.Statics) == 0 || len(exerIDs) == 0 {
+		return datatypes.Workout{}, ErrIncompleteWorkout
 	}
 
-	dynamics, statics, exercises, matrix, err := database.QueryWO(db, boltDB, WOBody.Difficulty == 1, WOBody.Statics, WOBody.Dynamics, exerIDRoundList)
+	dynamics, statics, exercises, matrix, err := database.QueryWO(db, boltDB, tenant, WOBody.Difficulty == 1, WOBody.Statics, WOBody.Dynamics, exerIDRoundList)
 	if err != nil {
 		return datatypes.Workout{}, err
 	}
 
-	if len(dynamics) == 0 || len(statics) == 0 || len(exercises) == 0 {
-		return datatypes.Workout{}, errors.New("unfilled dynamic/static/exercises returned")
+	unresolved := &UnresolvedIDsError{
+		Dynamics:  missingIDs(dynamics, WOBody.Dynamics),
+		Statics:   missingIDs(statics, WOBody.Statics),
+		Exercises: missingIDs(exercises, exerIDs),
+	}
+	if !unresolved.empty() {
+		unresolved.record()
+		return datatypes.Workout{}, unresolved
+	}
+
+	settings, err := database.GetTenantSettings(db, boltDB, tenant)
+	if err != nil {
+		return datatypes.Workout{}, err
+	}
+
+	theme, err := database.ResolveTheme(db, boltDB, tenant, WOBody.ThemeID, settings)
+	if err != nil {
+		return datatypes.Workout{}, themeError(WOBody.ThemeID, err)
 	}
 
 	dynamicSets, dynamicNames, dynamicSamples := DynamicSets(dynamics, WOBody.Dynamics, WOBody.StretchTimes)
@@ -60,22 +85,27 @@ func Workout(db *mongo.Database, boltDB *bbolt.DB, WOBody datatypes.WorkoutRoute
 		currentRound.RestPerSet = round.Times.RestPerSet
 		currentRound.ExerPerSet = round.Times.ExercisePerSet
 
+		roundStart := time.Now()
 		if round.Status == "Regular" {
 			currentRound.SetSlice, currentRound.SetSequence, currentRound.Reps = RegularRound(exercises, round)
+			metrics.RoundGeneration("Regular", roundStart)
 		} else if round.Status == "Combo" {
 			currentRound.SetSlice, currentRound.SetSequence, currentRound.Reps = ComboRound(exercises, round, matrix)
+			metrics.RoundGeneration("Combo", roundStart)
 		} else {
 			currentRound.SetSlice, currentRound.SetSequence, currentRound.Reps, currentRound.SplitPairs = SplitRound(exercises, round, matrix)
+			metrics.RoundGeneration("Split", roundStart)
 		}
 
-		currentRound.RestPosition = "resting-position"
+		currentRound.RestPosition = theme.RestPosition
 
 		retExers[i] = currentRound
 	}
 	workout.Exercises = retExers
 
-	workout.CongratsPosition = "standing-thumbs-up-wink"
-	workout.StandingPosition = "standing-arms-bent"
+	workout.CongratsPosition = theme.CongratsPosition
+	workout.StandingPosition = theme.StandingPosition
+	workout.CatalogVersion = settings.CatalogVersion
 
 	workout.BackendID = WOBody.ID.Hex()
 
@@ -629,27 +659,13 @@ func exerToRep(exer datatypes.Exercise, initRepTime float32, alternate bool) dat
 }
 
 func getSingleTransition(exer1, exer2 datatypes.Exercise, matrix datatypes.TransitionMatrix, speed string) datatypes.TransitionRep {
-	parentMatIndex := map[string]int{
-		"Pushups":           0,
-		"Squats":            1,
-		"Burpees":           2,
-		"Jumps":             3,
-		"Lunges":            4,
-		"Mountain Climbers": 5,
-		"Abs":               6,
-		"Bridges":           7,
-		"Kicks":             8,
-		"Planks":            9,
-		"Supermans":         10,
-	}
-
 	switch speed {
 	case "Slow":
-		return matrix.SlowMatrix[parentMatIndex[exer1.Parent]][parentMatIndex[exer2.Parent]]
+		return matrix.SlowMatrix[datatypes.ParentMatIndex[exer1.Parent]][datatypes.ParentMatIndex[exer2.Parent]]
 	case "Fast":
-		return matrix.FastMatrix[parentMatIndex[exer1.Parent]][parentMatIndex[exer2.Parent]]
+		return matrix.FastMatrix[datatypes.ParentMatIndex[exer1.Parent]][datatypes.ParentMatIndex[exer2.Parent]]
 	default:
-		return matrix.RegularMatrix[parentMatIndex[exer1.Parent]][parentMatIndex[exer2.Parent]]
+		return matrix.RegularMatrix[datatypes.ParentMatIndex[exer1.Parent]][datatypes.ParentMatIndex[exer2.Parent]]
 	}
 }
 
@@ -665,26 +681,12 @@ func transitionRepToRep(transition datatypes.TransitionRep) datatypes.Rep {
 
 func getTransitions(exercises map[string]datatypes.Exercise, round datatypes.WorkoutRound, matrix datatypes.TransitionMatrix) ([]datatypes.Rep, float32) {
 
-	parentMatIndex := map[string]int{
-		"Pushups":           0,
-		"Squats":            1,
-		"Burpees":           2,
-		"Jumps":             3,
-		"Lunges":            4,
-		"Mountain Climbers": 5,
-		"Abs":               6,
-		"Bridges":           7,
-		"Kicks":             8,
-		"Planks":            9,
-		"Supermans":         10,
-	}
-
 	transitions := []datatypes.Rep{}
 	workingTime := round.Times.ExercisePerSet
 
 	for i, exID := range round.ExerciseIDs {
 		if i != 0 {
-			index1, index2 := parentMatIndex[exercises[round.ExerciseIDs[i-1]].Parent], parentMatIndex[exercises[exID].Parent]
+			index1, index2 := datatypes.ParentMatIndex[exercises[round.ExerciseIDs[i-1]].Parent], datatypes.ParentMatIndex[exercises[exID].Parent]
 			transRep := matrix.RegularMatrix[index1][index2]
 
 			rep := datatypes.Rep{